@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/alex65536/yacontable/internal/fakecontest"
+)
+
+func main() {
+	listenAddr := flag.String("listen", "127.0.0.1:8081", "address to listen on")
+	fixtures := flag.String("fixtures", "internal/fakecontest/testdata", "directory with <contest_id>.json standings fixtures")
+	flag.Parse()
+
+	s := fakecontest.NewServer()
+	if err := s.LoadFixtures(*fixtures); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving fake contest API on %v", *listenAddr)
+	log.Fatal(http.ListenAndServe(*listenAddr, s))
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/alex65536/yacontable/internal"
 	"github.com/klauspost/compress/gzhttp"
@@ -37,7 +42,48 @@ func setupServers(conf *internal.Config) {
 	}
 }
 
+func registerRoutes(mux *http.ServeMux, pres *internal.Presenter) {
+	mux.Handle("/", gzhttp.GzipHandler(pres))
+	mux.Handle("/participant", gzhttp.GzipHandler(http.HandlerFunc(pres.ServeParticipant)))
+	mux.Handle("/teams", gzhttp.GzipHandler(http.HandlerFunc(pres.ServeTeams)))
+	mux.Handle("/api/v1/standings", gzhttp.GzipHandler(http.HandlerFunc(pres.ServeJSON)))
+	mux.Handle("/api/v1/teams", gzhttp.GzipHandler(http.HandlerFunc(pres.ServeTeamsJSON)))
+	mux.Handle("/export.csv", gzhttp.GzipHandler(pres.ExportHandler(internal.ExportFormatCSV)))
+	mux.Handle("/export.tsv", gzhttp.GzipHandler(pres.ExportHandler(internal.ExportFormatTSV)))
+	mux.HandleFunc("/export.xlsx", pres.ServeXLSX)
+	mux.Handle("/style.css", gzhttp.GzipHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.ServeFile(w, req, "./data/style.css")
+	})))
+	mux.Handle("/favicon.ico", gzhttp.GzipHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.ServeFile(w, req, "./data/favicon.ico")
+	})))
+}
+
+func checkConfig(args []string) int {
+	name := "config.json"
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if _, err := internal.LoadConfigFrom(name); err != nil {
+		var confErrs internal.ConfigErrors
+		if errors.As(err, &confErrs) {
+			for _, e := range confErrs {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", name, e)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
+		}
+		return 1
+	}
+	fmt.Printf("%v: config is OK\n", name)
+	return 0
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		os.Exit(checkConfig(os.Args[2:]))
+	}
+
 	logger, err := zap.NewProduction()
 	if err != nil {
 		panic(err)
@@ -51,34 +97,75 @@ func main() {
 		panic(err)
 	}
 
-	setupServers(conf)
+	store := internal.NewConfigStore(conf)
 
-	api, err := internal.NewApi(logger, context.Background(), conf, sec)
+	// Create the API client even if there are no Yandex contests, so they can be added from the admin panel later.
+	api, err := internal.NewApi(logger, context.Background(), store, sec)
 	if err != nil {
 		panic(err)
 	}
 
-	keep, err := internal.NewKeeper(conf, api)
+	keep, err := internal.NewKeeper(logger, store, api)
 	if err != nil {
 		panic(err)
 	}
+	go keep.Run(context.Background())
+
+	reloader := internal.NewConfigReloader(logger, store)
+	go reloader.Watch(context.Background())
 
-	pres, err := internal.NewPresenter(context.Background(), logger, keep, conf)
+	go func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGUSR1, syscall.SIGHUP)
+		for sig := range ch {
+			switch sig {
+			case syscall.SIGUSR1:
+				logger.Info("got SIGUSR1, refreshing standings")
+				keep.Refresh()
+			case syscall.SIGHUP:
+				logger.Info("got SIGHUP, reloading config")
+				if err := reloader.Reload(); err != nil {
+					logger.Error("cannot reload config, keeping the old one", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	auth := api.Authorizer()
+	if auth != nil {
+		auth.OnAuthorized(keep.Refresh)
+	}
+	store.Subscribe(func(*internal.Config) {
+		keep.Refresh()
+	})
+
+	pres, err := internal.NewPresenter(logger, keep, auth, store)
 	if err != nil {
 		panic(err)
 	}
 
-	http.Handle("/", gzhttp.GzipHandler(pres))
-	http.Handle("/style.css", gzhttp.GzipHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		http.ServeFile(w, req, "./data/style.css")
-	})))
+	registerRoutes(http.DefaultServeMux, pres)
+	juryMux := http.NewServeMux()
+	registerRoutes(juryMux, pres)
+	juryMux.HandleFunc("/unfreeze", pres.ServeUnfreeze)
+	http.Handle("/jury/", internal.JuryHandler(logger, sec.JuryPassword, http.StripPrefix("/jury", juryMux)))
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("/", pres.ServeAdmin)
+	adminMux.HandleFunc("/auth", pres.ServeAdminAuth)
+	adminMux.HandleFunc("/refresh", pres.ServeAdminRefresh)
+	adminMux.HandleFunc("/display", pres.ServeAdminDisplay)
+	adminMux.HandleFunc("/add-contest", pres.ServeAdminAddContest)
+	adminMux.HandleFunc("/remove-contest", pres.ServeAdminRemoveContest)
+	http.Handle("/admin/", internal.AdminHandler(logger, sec.AdminPassword, http.StripPrefix("/admin", adminMux)))
+	if auth != nil {
+		http.HandleFunc("/authCallback", auth.ServeCallback)
+	}
 	http.HandleFunc("/robots.txt", func(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		_, _ = io.WriteString(w, "not found")
 	})
-	http.Handle("/favicon.ico", gzhttp.GzipHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		http.ServeFile(w, req, "./data/favicon.ico")
-	})))
+
+	setupServers(conf)
 
 	<-make(chan struct{})
 }
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type adminContest struct {
+	Index   int
+	Key     string
+	Contest Contest
+	Status  *ContestStatus
+}
+
+func (p *Presenter) notAuthorized() bool {
+	return p.auth != nil && p.conf().UsesYandexApi() && !p.auth.Status().Authorized
+}
+
+func (p *Presenter) doBuildAdmin() ([]byte, error) {
+	type state struct {
+		UsesApi  bool
+		Auth     AuthStatus
+		Conf     *Config
+		Contests []adminContest
+		Snapshot *Snapshot
+		Types    []string
+	}
+
+	conf := p.conf()
+	snap := p.k.JurySnapshot()
+	st := state{
+		UsesApi:  p.auth != nil,
+		Conf:     conf,
+		Contests: make([]adminContest, len(conf.Contests)),
+		Snapshot: snap,
+		Types:    []string{ContestTypeYandex, ContestTypeURL, ContestTypeFile},
+	}
+	if p.auth != nil {
+		st.Auth = p.auth.Status()
+	}
+	for i, ct := range conf.Contests {
+		st.Contests[i] = adminContest{
+			Index:   i,
+			Key:     ct.sourceKey(),
+			Contest: ct,
+		}
+		for j := range snap.Contests {
+			if snap.Contests[j].Contest.sourceKey() == ct.sourceKey() {
+				st.Contests[i].Status = &snap.Contests[j]
+				break
+			}
+		}
+	}
+	var b bytes.Buffer
+	err := p.t.ExecuteTemplate(&b, "admin.html", &st)
+	if err != nil {
+		return nil, fmt.Errorf("building template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (p *Presenter) checkAdmin(w http.ResponseWriter, req *http.Request, method string) bool {
+	p.logger.Info("admin", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if !isAdmin(req) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "forbidden")
+		return false
+	}
+	if req.Method != method {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "use "+method+" method")
+		return false
+	}
+	return true
+}
+
+// redirectToIndex redirects to the index page of the area (like /admin/ or /jury/) mounted with http.StripPrefix.
+func redirectToIndex(w http.ResponseWriter) {
+	// http.Redirect would resolve the relative URL against the stripped path, so set it directly.
+	w.Header().Set("Location", "./")
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+func (p *Presenter) ServeAdmin(w http.ResponseWriter, req *http.Request) {
+	if !p.checkAdmin(w, req, http.MethodGet) {
+		return
+	}
+	if req.URL.Path != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "not found")
+		return
+	}
+	b, err := p.doBuildAdmin()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		p.logger.Error("error serving request", zap.Error(err))
+		_, _ = io.WriteString(w, "got error: "+err.Error())
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func (p *Presenter) ServeAdminAuth(w http.ResponseWriter, req *http.Request) {
+	if !p.checkAdmin(w, req, http.MethodPost) {
+		return
+	}
+	if p.auth == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "contest API is not used")
+		return
+	}
+	http.Redirect(w, req, p.auth.Start(), http.StatusSeeOther)
+}
+
+func (p *Presenter) ServeAdminRefresh(w http.ResponseWriter, req *http.Request) {
+	if !p.checkAdmin(w, req, http.MethodPost) {
+		return
+	}
+	p.k.Refresh()
+	redirectToIndex(w)
+}
+
+func (p *Presenter) ServeAdminDisplay(w http.ResponseWriter, req *http.Request) {
+	if !p.checkAdmin(w, req, http.MethodPost) {
+		return
+	}
+	hideLogins := req.PostFormValue("hide_logins") != ""
+	displayNames := req.PostFormValue("display_names") != ""
+	displayTeams := req.PostFormValue("display_teams") != ""
+	err := p.store.Update(func(conf *Config) error {
+		conf.HideLogins = hideLogins
+		conf.DisplayNames = displayNames
+		conf.DisplayTeams = displayTeams
+		return nil
+	})
+	if err != nil {
+		p.writeAdminError(w, err)
+		return
+	}
+	p.logger.Info("display settings changed", zap.Bool("hide_logins", hideLogins), zap.Bool("display_names", displayNames), zap.Bool("display_teams", displayTeams))
+	redirectToIndex(w)
+}
+
+var errBadAdminRequest = errors.New("bad request")
+
+func parseAdminContest(req *http.Request) (Contest, error) {
+	ct := Contest{
+		Type: req.PostFormValue("type"),
+		Tag:  strings.TrimSpace(req.PostFormValue("tag")),
+		URL:  strings.TrimSpace(req.PostFormValue("url")),
+		Path: strings.TrimSpace(req.PostFormValue("path")),
+	}
+	if idStr := strings.TrimSpace(req.PostFormValue("id")); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 0)
+		if err != nil {
+			return Contest{}, fmt.Errorf("%w: bad contest id: %w", errBadAdminRequest, err)
+		}
+		ct.ID = int(id)
+	}
+	// Keep only the field that identifies the contest source for its type.
+	switch ct.Type {
+	case ContestTypeYandex:
+		ct.URL, ct.Path = "", ""
+	case ContestTypeURL:
+		ct.ID, ct.Path = 0, ""
+	case ContestTypeFile:
+		ct.ID, ct.URL = 0, ""
+	}
+	if err := validateContest(ct); err != nil {
+		return Contest{}, fmt.Errorf("%w: %w", errBadAdminRequest, err)
+	}
+	return ct, nil
+}
+
+func (p *Presenter) writeAdminError(w http.ResponseWriter, err error) {
+	var confErrs ConfigErrors
+	if errors.Is(err, errBadAdminRequest) || errors.As(err, &confErrs) {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+		p.logger.Error("error serving admin request", zap.Error(err))
+	}
+	_, _ = io.WriteString(w, "got error: "+err.Error())
+}
+
+func (p *Presenter) ServeAdminAddContest(w http.ResponseWriter, req *http.Request) {
+	if !p.checkAdmin(w, req, http.MethodPost) {
+		return
+	}
+	ct, err := parseAdminContest(req)
+	if err != nil {
+		p.writeAdminError(w, err)
+		return
+	}
+	err = p.store.Update(func(conf *Config) error {
+		for _, other := range conf.Contests {
+			if other.sourceKey() == ct.sourceKey() {
+				return fmt.Errorf("%w: %v is already added", errBadAdminRequest, ct)
+			}
+		}
+		conf.Contests = append(conf.Contests, ct)
+		return nil
+	})
+	if err != nil {
+		p.writeAdminError(w, err)
+		return
+	}
+	p.logger.Info("contest added", zap.Stringer("contest", ct))
+	redirectToIndex(w)
+}
+
+func (p *Presenter) ServeAdminRemoveContest(w http.ResponseWriter, req *http.Request) {
+	if !p.checkAdmin(w, req, http.MethodPost) {
+		return
+	}
+	key := req.PostFormValue("key")
+	var removed Contest
+	err := p.store.Update(func(conf *Config) error {
+		for i, ct := range conf.Contests {
+			if ct.sourceKey() == key {
+				removed = ct
+				conf.Contests = append(conf.Contests[:i], conf.Contests[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: contest not found", errBadAdminRequest)
+	})
+	if err != nil {
+		p.writeAdminError(w, err)
+		return
+	}
+	p.logger.Info("contest removed", zap.Stringer("contest", removed))
+	redirectToIndex(w)
+}
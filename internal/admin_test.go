@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func postAdmin(h http.HandlerFunc, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h(w, req.WithContext(context.WithValue(req.Context(), adminKey{}, true)))
+	return w
+}
+
+func contestKeys(conf *Config) []string {
+	keys := make([]string, len(conf.Contests))
+	for i, ct := range conf.Contests {
+		keys[i] = ct.sourceKey()
+	}
+	return keys
+}
+
+func TestAdminAddContest(t *testing.T) {
+	env := newTestEnv(t, nil)
+	p := env.presenter(t)
+	before := contestKeys(env.store.Get())
+
+	w := serve(http.HandlerFunc(p.ServeAdminAddContest), "/add-contest")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("got status %v without admin rights, want %v", w.Code, http.StatusForbidden)
+	}
+
+	tests := []struct {
+		name string
+		form url.Values
+		want int
+	}{
+		{name: "bad id", form: url.Values{"type": {ContestTypeYandex}, "id": {"abc"}}, want: http.StatusBadRequest},
+		{name: "no id", form: url.Values{"type": {ContestTypeYandex}}, want: http.StatusBadRequest},
+		{name: "already added", form: url.Values{"type": {ContestTypeYandex}, "id": {"1"}}, want: http.StatusBadRequest},
+		{name: "used tag", form: url.Values{"type": {ContestTypeYandex}, "id": {"3"}, "tag": {"D1"}}, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := postAdmin(p.ServeAdminAddContest, tt.form)
+		if w.Code != tt.want {
+			t.Errorf("%v: got status %v, want %v", tt.name, w.Code, tt.want)
+		}
+		if got := contestKeys(env.store.Get()); !slices.Equal(got, before) {
+			t.Errorf("%v: got contests %v, want %v", tt.name, got, before)
+		}
+	}
+
+	// The fields of other contest types are dropped.
+	w = postAdmin(p.ServeAdminAddContest, url.Values{"type": {ContestTypeYandex}, "id": {"3"}, "tag": {"D3"}, "path": {"d3.json"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	conf := env.store.Get()
+	if len(conf.Contests) != 3 {
+		t.Fatalf("got contests %v, want 3 of them", conf.Contests)
+	}
+	if ct := conf.Contests[2]; ct.ID != 3 || ct.Tag != "D3" || ct.Path != "" {
+		t.Errorf("got added contest %+v", ct)
+	}
+}
+
+func TestAdminRemoveContest(t *testing.T) {
+	env := newTestEnv(t, nil)
+	p := env.presenter(t)
+	keys := contestKeys(env.store.Get())
+
+	w := postAdmin(p.ServeAdminRemoveContest, url.Values{"key": {"unknown"}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %v for unknown contest, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	w = postAdmin(p.ServeAdminRemoveContest, url.Values{"key": {keys[0]}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	if got := contestKeys(env.store.Get()); !slices.Equal(got, keys[1:]) {
+		t.Errorf("got contests %v, want %v", got, keys[1:])
+	}
+
+	w = postAdmin(p.ServeAdminRemoveContest, url.Values{"key": {keys[0]}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %v when removing the contest again, want %v", w.Code, http.StatusBadRequest)
+	}
+}
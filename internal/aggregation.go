@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/alex65536/yacontable/pkg/goutil"
+)
+
+type Day struct {
+	Title    string `json:"title"`
+	Contests []int  `json:"contests"`
+}
+
+type DayResult struct {
+	Score   float64 `json:"score"`
+	Raw     float64 `json:"raw"`
+	Contest int     `json:"contest"`
+	Counted bool    `json:"counted"`
+}
+
+type Aggregation struct {
+	Days          []Day             `json:"days"`
+	Weights       []float64         `json:"weights"`
+	BestK         int               `json:"best_k,omitempty"`
+	Normalization NormalizationMode `json:"normalization,omitempty"`
+}
+
+func NewAggregation(conf *Config) *Aggregation {
+	trivial := conf.Aggregation.BestK == 0 && conf.Aggregation.Normalization == NormalizationNone
+	res := &Aggregation{
+		Weights:       make([]float64, len(conf.Contests)),
+		BestK:         conf.Aggregation.BestK,
+		Normalization: conf.Aggregation.Normalization,
+	}
+	groups := make(map[string]int)
+	for i, ct := range conf.Contests {
+		res.Weights[i] = 1.0
+		if ct.Weight != nil {
+			res.Weights[i] = *ct.Weight
+			trivial = false
+		}
+		if ct.Group != "" {
+			if day, ok := groups[ct.Group]; ok {
+				res.Days[day].Contests = append(res.Days[day].Contests, i)
+				trivial = false
+				continue
+			}
+			groups[ct.Group] = len(res.Days)
+		}
+		res.Days = append(res.Days, Day{Contests: []int{i}})
+	}
+	if trivial {
+		return nil
+	}
+	for i := range res.Days {
+		d := &res.Days[i]
+		d.Title = strings.Join(goutil.Map(d.Contests, func(c int) string {
+			return conf.Contests[c].String()
+		}), "/")
+	}
+	return res
+}
+
+func (s *Standings) contestScores(taskScores []float64, numContests int) []float64 {
+	res := make([]float64, numContests)
+	for j, score := range taskScores {
+		if c := s.Header.Tasks[j].Contest; c < numContests {
+			res[c] += score
+		}
+	}
+	return res
+}
+
+func (a *Aggregation) aggregate(rawScores, contestScores []float64) ([]DayResult, float64) {
+	days := make([]DayResult, len(a.Days))
+	for j, d := range a.Days {
+		for k, c := range d.Contests {
+			score := contestScores[c] * a.Weights[c]
+			if k == 0 || score > days[j].Score {
+				days[j].Score = score
+				days[j].Raw = rawScores[c]
+				days[j].Contest = c
+			}
+		}
+	}
+
+	order := make([]int, len(a.Days))
+	for j := range order {
+		order[j] = j
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		if days[x].Score > days[y].Score {
+			return -1
+		}
+		if days[x].Score < days[y].Score {
+			return 1
+		}
+		return 0
+	})
+	if a.BestK > 0 && a.BestK < len(order) {
+		order = order[:a.BestK]
+	}
+	total := 0.0
+	for _, j := range order {
+		days[j].Counted = true
+		total += days[j].Score
+	}
+	return days, total
+}
+
+func (a *Aggregation) apply(s *Standings) error {
+	scores := goutil.Map(s.Participants, func(p Participant) []float64 {
+		return s.contestScores(goutil.Map(p.Tasks, func(c ParticipantCell) float64 {
+			return c.Score
+		}), len(a.Weights))
+	})
+	var norm *normalization
+	if a.Normalization != NormalizationNone {
+		var err error
+		norm, err = newNormalization(a.Normalization, s, scores, len(a.Weights))
+		if err != nil {
+			return err
+		}
+	}
+	for i := range s.Participants {
+		p := &s.Participants[i]
+		contestScores := scores[i]
+		if norm != nil {
+			contestScores = norm.apply(contestScores)
+		}
+		p.Days, p.Total = a.aggregate(scores[i], contestScores)
+	}
+	return nil
+}
+
+func (a *Aggregation) maxTotal(s *Standings) (float64, bool) {
+	var contestMax []float64
+	switch a.Normalization {
+	case NormalizationNone:
+		var err error
+		contestMax, err = contestMaxScores(s, len(a.Weights))
+		if err != nil {
+			return 0.0, false
+		}
+	case NormalizationMax, NormalizationBest:
+		contestMax = make([]float64, len(a.Weights))
+		for i := range contestMax {
+			contestMax[i] = 1.0
+		}
+	default:
+		return 0.0, false
+	}
+	_, total := a.aggregate(contestMax, contestMax)
+	return total, true
+}
+
+func (s *Standings) IsNormalized() bool {
+	return s.Aggregation != nil && s.Aggregation.Normalization != NormalizationNone
+}
+
+func (s *Standings) SetAggregation(a *Aggregation) error {
+	s.Aggregation = a
+	return s.ValidateAndFix()
+}
+
+func (s *Standings) TaskCounted(participant, task int) bool {
+	if s.Aggregation == nil {
+		return true
+	}
+	c := s.Header.Tasks[task].Contest
+	for _, d := range s.Participants[participant].Days {
+		if d.Contest == c {
+			return d.Counted
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewAggregation(t *testing.T) {
+	weight := 2.0
+	tests := []struct {
+		name   string
+		conf   Config
+		want   *Aggregation
+		titles []string
+	}{
+		{
+			name: "trivial",
+			conf: Config{Contests: []Contest{{ID: 1}, {ID: 2}}},
+		},
+		{
+			name: "best k",
+			conf: Config{
+				Contests:    []Contest{{ID: 1, Tag: "D1"}, {ID: 2, Tag: "D2"}},
+				Aggregation: AggregationConfig{BestK: 1},
+			},
+			want: &Aggregation{
+				Days:    []Day{{Title: "D1", Contests: []int{0}}, {Title: "D2", Contests: []int{1}}},
+				Weights: []float64{1, 1},
+				BestK:   1,
+			},
+		},
+		{
+			name: "weights",
+			conf: Config{Contests: []Contest{{ID: 1, Tag: "D1", Weight: &weight}, {ID: 2, Tag: "D2"}}},
+			want: &Aggregation{
+				Days:    []Day{{Title: "D1", Contests: []int{0}}, {Title: "D2", Contests: []int{1}}},
+				Weights: []float64{2, 1},
+			},
+		},
+		{
+			name: "groups",
+			conf: Config{Contests: []Contest{
+				{ID: 1, Tag: "D1a", Group: "d1"},
+				{ID: 2, Tag: "D2"},
+				{ID: 3, Tag: "D1b", Group: "d1"},
+			}},
+			want: &Aggregation{
+				Days:    []Day{{Title: "D1a/D1b", Contests: []int{0, 2}}, {Title: "D2", Contests: []int{1}}},
+				Weights: []float64{1, 1, 1},
+			},
+		},
+		{
+			name: "single contest groups are trivial",
+			conf: Config{Contests: []Contest{{ID: 1, Group: "d1"}, {ID: 2, Group: "d2"}}},
+		},
+	}
+	for _, tt := range tests {
+		got := NewAggregation(&tt.conf)
+		if (got == nil) != (tt.want == nil) {
+			t.Errorf("%v: got %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		if got == nil {
+			continue
+		}
+		if got.BestK != tt.want.BestK || got.Normalization != tt.want.Normalization || !slices.Equal(got.Weights, tt.want.Weights) {
+			t.Errorf("%v: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		if !slices.EqualFunc(got.Days, tt.want.Days, func(a, b Day) bool {
+			return a.Title == b.Title && slices.Equal(a.Contests, b.Contests)
+		}) {
+			t.Errorf("%v: got days %+v, want %+v", tt.name, got.Days, tt.want.Days)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	oneContestDays := []Day{{Contests: []int{0}}, {Contests: []int{1}}, {Contests: []int{2}}}
+	tests := []struct {
+		name      string
+		agg       Aggregation
+		raw       []float64
+		scores    []float64
+		wantDays  []DayResult
+		wantTotal float64
+	}{
+		{
+			name:   "sum",
+			agg:    Aggregation{Days: oneContestDays, Weights: []float64{1, 1, 1}},
+			raw:    []float64{10, 30, 20},
+			scores: []float64{10, 30, 20},
+			wantDays: []DayResult{
+				{Score: 10, Raw: 10, Contest: 0, Counted: true},
+				{Score: 30, Raw: 30, Contest: 1, Counted: true},
+				{Score: 20, Raw: 20, Contest: 2, Counted: true},
+			},
+			wantTotal: 60,
+		},
+		{
+			name:   "best k",
+			agg:    Aggregation{Days: oneContestDays, Weights: []float64{1, 1, 1}, BestK: 2},
+			raw:    []float64{10, 30, 20},
+			scores: []float64{10, 30, 20},
+			wantDays: []DayResult{
+				{Score: 10, Raw: 10, Contest: 0},
+				{Score: 30, Raw: 30, Contest: 1, Counted: true},
+				{Score: 20, Raw: 20, Contest: 2, Counted: true},
+			},
+			wantTotal: 50,
+		},
+		{
+			name:   "best k with ties keeps earlier days",
+			agg:    Aggregation{Days: oneContestDays, Weights: []float64{1, 1, 1}, BestK: 1},
+			raw:    []float64{20, 20, 10},
+			scores: []float64{20, 20, 10},
+			wantDays: []DayResult{
+				{Score: 20, Raw: 20, Contest: 0, Counted: true},
+				{Score: 20, Raw: 20, Contest: 1},
+				{Score: 10, Raw: 10, Contest: 2},
+			},
+			wantTotal: 20,
+		},
+		{
+			name:   "best k above number of days",
+			agg:    Aggregation{Days: oneContestDays, Weights: []float64{1, 1, 1}, BestK: 5},
+			raw:    []float64{1, 2, 3},
+			scores: []float64{1, 2, 3},
+			wantDays: []DayResult{
+				{Score: 1, Raw: 1, Contest: 0, Counted: true},
+				{Score: 2, Raw: 2, Contest: 1, Counted: true},
+				{Score: 3, Raw: 3, Contest: 2, Counted: true},
+			},
+			wantTotal: 6,
+		},
+		{
+			name:   "weights change best k choice",
+			agg:    Aggregation{Days: oneContestDays, Weights: []float64{3, 1, 1}, BestK: 1},
+			raw:    []float64{10, 25, 20},
+			scores: []float64{10, 25, 20},
+			wantDays: []DayResult{
+				{Score: 30, Raw: 10, Contest: 0, Counted: true},
+				{Score: 25, Raw: 25, Contest: 1},
+				{Score: 20, Raw: 20, Contest: 2},
+			},
+			wantTotal: 30,
+		},
+		{
+			name: "group takes best weighted contest",
+			agg: Aggregation{
+				Days:    []Day{{Contests: []int{0, 1}}, {Contests: []int{2}}},
+				Weights: []float64{1, 2, 1},
+			},
+			raw:    []float64{50, 30, 10},
+			scores: []float64{50, 30, 10},
+			wantDays: []DayResult{
+				{Score: 60, Raw: 30, Contest: 1, Counted: true},
+				{Score: 10, Raw: 10, Contest: 2, Counted: true},
+			},
+			wantTotal: 70,
+		},
+		{
+			name: "group with equal scores takes first contest",
+			agg: Aggregation{
+				Days:    []Day{{Contests: []int{0, 1}}},
+				Weights: []float64{1, 1},
+			},
+			raw:    []float64{0, 0},
+			scores: []float64{0, 0},
+			wantDays: []DayResult{
+				{Score: 0, Raw: 0, Contest: 0, Counted: true},
+			},
+			wantTotal: 0,
+		},
+		{
+			name:   "normalized scores keep raw values",
+			agg:    Aggregation{Days: oneContestDays, Weights: []float64{1, 1, 1}, BestK: 2},
+			raw:    []float64{50, 100, 80},
+			scores: []float64{0.5, 0.25, 1},
+			wantDays: []DayResult{
+				{Score: 0.5, Raw: 50, Contest: 0, Counted: true},
+				{Score: 0.25, Raw: 100, Contest: 1},
+				{Score: 1, Raw: 80, Contest: 2, Counted: true},
+			},
+			wantTotal: 1.5,
+		},
+	}
+	for _, tt := range tests {
+		days, total := tt.agg.aggregate(tt.raw, tt.scores)
+		if !slices.Equal(days, tt.wantDays) {
+			t.Errorf("%v: got days %+v, want %+v", tt.name, days, tt.wantDays)
+		}
+		if total != tt.wantTotal {
+			t.Errorf("%v: got total %v, want %v", tt.name, total, tt.wantTotal)
+		}
+	}
+}
@@ -2,14 +2,12 @@ package internal
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
-	"math/big"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -22,11 +20,23 @@ import (
 
 type Api struct {
 	client *http.Client
-	conf   *Config
+	tokens *persistingTokenSource
+	auth   *Authorizer
+	store  *ConfigStore
 	logger *zap.Logger
 }
 
-func NewApi(logger *zap.Logger, ctx context.Context, conf *Config, s *StaticSecrets) (*Api, error) {
+func NewApi(logger *zap.Logger, ctx context.Context, store *ConfigStore, s *StaticSecrets) (*Api, error) {
+	conf := store.Get()
+	if conf.ApiNoAuth {
+		logger.Warn("using contest API without authorization", zap.String("base_url", conf.ApiBaseURL))
+		return &Api{
+			client: &http.Client{},
+			store:  store,
+			logger: logger,
+		}, nil
+	}
+
 	d, err := LoadDynamicSecrets()
 	if err != nil {
 		return nil, fmt.Errorf("loading dynamic secrets: %w", err)
@@ -41,23 +51,23 @@ func NewApi(logger *zap.Logger, ctx context.Context, conf *Config, s *StaticSecr
 	}
 
 	if d.Token == nil {
-		d, err = fetchTokens(logger, ctx, conf, oauthConf, s)
-		if err != nil {
-			return nil, fmt.Errorf("requesting oauth2 tokens: %w", err)
-		}
-		err = StoreDynamicSecrets(d)
-		if err != nil {
-			return nil, fmt.Errorf("storing dynamic secrets: %w", err)
-		}
+		logger.Warn("contest API is not authorized yet, visit the admin page to connect Yandex account")
 	}
+	tokens := newPersistingTokenSource(logger, ctx, oauthConf, d.Token)
 
 	return &Api{
-		client: oauthConf.Client(ctx, d.Token),
-		conf:   conf,
+		client: oauth2.NewClient(ctx, tokens),
+		tokens: tokens,
+		auth:   newAuthorizer(logger, oauthConf, tokens),
+		store:  store,
 		logger: logger,
 	}, nil
 }
 
+func (a *Api) Authorizer() *Authorizer {
+	return a.auth
+}
+
 func parseScore(src string) (float64, error) {
 	if src == "" {
 		return 0.0, nil
@@ -69,39 +79,66 @@ func parseScore(src string) (float64, error) {
 	return strconv.ParseFloat(src, 64)
 }
 
-func (a *Api) FetchStandings(contest Contest) (*Standings, error) {
-	type title struct {
-		Name  string `json:"name"`
-		Title string `json:"title"`
-	}
+type apiTitle struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
 
-	type partInfo struct {
-		Login string `json:"login"`
-		Name  string `json:"name"`
-	}
+type apiParticipantInfo struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
 
-	type problemResult struct {
-		Score string `json:"score"`
-	}
+type apiInt int
 
-	type row struct {
-		ParticipantInfo partInfo        `json:"participantInfo"`
-		ProblemResults  []problemResult `json:"problemResults"`
+func (v *apiInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*v = 0
+		return nil
 	}
-
-	type standings struct {
-		Titles []title `json:"titles"`
-		Rows   []row   `json:"rows"`
+	res, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("decoding int: %w", err)
 	}
+	*v = apiInt(res)
+	return nil
+}
 
+const apiStatusAccepted = "ACCEPTED"
+
+type apiProblemResult struct {
+	Score           string `json:"score"`
+	Status          string `json:"status"`
+	SubmissionCount apiInt `json:"submissionCount"`
+	SubmitDelay     apiInt `json:"submitDelay"`
+}
+
+type apiRow struct {
+	ParticipantInfo apiParticipantInfo `json:"participantInfo"`
+	ProblemResults  []apiProblemResult `json:"problemResults"`
+}
+
+type apiStandings struct {
+	Titles []apiTitle `json:"titles"`
+	Rows   []apiRow   `json:"rows"`
+}
+
+func (a *Api) fetchStandingsPage(ctx context.Context, conf *Config, contest Contest, page int) (*apiStandings, error) {
 	v := url.Values{}
-	v.Add("forJudge", fmt.Sprintf("%v", a.conf.StandingsForJudge))
-	v.Add("page", "1")
-	v.Add("pageSize", fmt.Sprintf("%v", a.conf.PageSize))
+	v.Add("forJudge", fmt.Sprintf("%v", conf.StandingsForJudge))
+	v.Add("page", fmt.Sprintf("%v", page))
+	v.Add("pageSize", fmt.Sprintf("%v", conf.PageSize))
 
-	rsp, err := a.client.Get(fmt.Sprintf("https://api.contest.yandex.net/api/public/v2/contests/%v/standings?%v", contest.ID, v.Encode()))
+	ctx, cancel := context.WithTimeout(ctx, conf.RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/api/public/v2/contests/%v/standings?%v", conf.ApiBaseURL, contest.ID, v.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	rsp, err := a.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("sending request to api: %w", err)
+		return nil, classifyTransportError(fmt.Errorf("sending request to api: %w", err))
 	}
 	defer func() {
 		_, _ = io.Copy(io.Discard, rsp.Body)
@@ -110,24 +147,79 @@ func (a *Api) FetchStandings(contest Contest) (*Standings, error) {
 	if rsp.StatusCode != http.StatusOK {
 		data, _ := io.ReadAll(rsp.Body)
 		a.logger.Error("non-ok response body", zap.String("data", string(data)))
-		return nil, fmt.Errorf("got non-ok status from contest API: %v %v", rsp.StatusCode, rsp.Status)
+		if rsp.StatusCode == http.StatusUnauthorized && a.tokens != nil {
+			a.tokens.Invalidate()
+		}
+		return nil, classifyStatus(rsp, fmt.Errorf("got non-ok status from contest API: %v %v", rsp.StatusCode, rsp.Status))
 	}
 
 	d := json.NewDecoder(rsp.Body)
-	var st standings
+	var st apiStandings
 	err = d.Decode(&st)
 	if err != nil {
-		return nil, fmt.Errorf("decoding json standings: %w", err)
+		return nil, classifyBodyError(fmt.Errorf("decoding json standings: %w", err))
+	}
+	return &st, nil
+}
+
+func (a *Api) fetchAllStandingsPages(ctx context.Context, contest Contest) (*apiStandings, error) {
+	conf := a.store.Get()
+	var res *apiStandings
+	logins := make(map[string]int)
+	for page := 1; ; page++ {
+		st, err := withRetry(ctx, a.logger, &conf.Retry, func(ctx context.Context) (*apiStandings, error) {
+			return a.fetchStandingsPage(ctx, conf, contest, page)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %v: %w", page, err)
+		}
+		if len(st.Rows) > conf.PageSize {
+			return nil, fmt.Errorf("page %v has %v rows, but page size is %v", page, len(st.Rows), conf.PageSize)
+		}
+		if res == nil {
+			res = st
+		} else {
+			if !slices.Equal(res.Titles, st.Titles) {
+				return nil, fmt.Errorf("page %v has different task titles than page 1", page)
+			}
+			res.Rows = append(res.Rows, st.Rows...)
+		}
+		for _, r := range st.Rows {
+			if prev, ok := logins[r.ParticipantInfo.Login]; ok {
+				return nil, fmt.Errorf("participant %q appears both on page %v and page %v, standings changed while fetching", r.ParticipantInfo.Login, prev, page)
+			}
+			logins[r.ParticipantInfo.Login] = page
+		}
+		if len(st.Rows) < conf.PageSize {
+			break
+		}
+	}
+	return res, nil
+}
+
+func (a *Api) FetchStandings(ctx context.Context, contest Contest) (*Standings, error) {
+	st, err := a.fetchAllStandingsPages(ctx, contest)
+	if err != nil {
+		return nil, err
 	}
 
-	participants, err := goutil.MapWithErr(st.Rows, func(r row) (Participant, error) {
-		tasks, err := goutil.MapWithErr(r.ProblemResults, func(p problemResult) (ParticipantCell, error) {
+	participants, err := goutil.MapWithErr(st.Rows, func(r apiRow) (Participant, error) {
+		tasks, err := goutil.MapWithErr(r.ProblemResults, func(p apiProblemResult) (ParticipantCell, error) {
 			score, err := parseScore(p.Score)
 			if err != nil {
 				return ParticipantCell{}, fmt.Errorf("decoding float score %q: %w", p.Score, err)
 			}
+			accepted := p.Status == apiStatusAccepted
+			time := 0
+			if accepted {
+				// submitDelay is measured in seconds since contest start.
+				time = int(p.SubmitDelay) / 60
+			}
 			return ParticipantCell{
-				Score: score,
+				Score:    score,
+				Accepted: accepted,
+				Attempts: int(p.SubmissionCount),
+				Time:     time,
 			}, nil
 		})
 		if err != nil {
@@ -146,7 +238,7 @@ func (a *Api) FetchStandings(contest Contest) (*Standings, error) {
 	res := &Standings{
 		Tag: contest.Tag,
 		Header: Header{
-			Tasks: goutil.Map(st.Titles, func(t title) TaskHeader {
+			Tasks: goutil.Map(st.Titles, func(t apiTitle) TaskHeader {
 				return TaskHeader{
 					Name:  t.Name,
 					Title: t.Title,
@@ -163,71 +255,3 @@ func (a *Api) FetchStandings(contest Contest) (*Standings, error) {
 
 	return res, nil
 }
-
-func fetchTokens(logger *zap.Logger, ctx context.Context, conf *Config, oauthConf *oauth2.Config, s *StaticSecrets) (*DynamicSecrets, error) {
-	verifier := oauth2.GenerateVerifier()
-	url := oauthConf.AuthCodeURL(callback.state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
-	logger.Info("generated auth code URL", zap.String("url", url))
-	fmt.Printf("to authorize, please visit %v\n", url)
-	code := <-callback.codes
-	tok, err := oauthConf.Exchange(ctx, code, oauth2.VerifierOption(verifier))
-	if err != nil {
-		return nil, fmt.Errorf("cannot exchange code for token: %w", err)
-	}
-	tok.TokenType = "OAuth" // HACK
-	return &DynamicSecrets{
-		Token: tok,
-	}, nil
-}
-
-type authCallback struct {
-	state string
-	codes chan string
-}
-
-func genState() string {
-	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	var b strings.Builder
-	for i := 0; i < 24; i++ {
-		pos, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
-		if err != nil {
-			panic(fmt.Errorf("cannot generate state: %w", err))
-		}
-		_ = b.WriteByte(letters[pos.Int64()])
-	}
-	return b.String()
-}
-
-var callback *authCallback = &authCallback{
-	state: genState(),
-	codes: make(chan string, 1000),
-}
-
-func init() {
-	http.HandleFunc("/authCallback", func(w http.ResponseWriter, req *http.Request) {
-		if req.Method != http.MethodGet {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			_, _ = io.WriteString(w, "use GET method")
-			return
-		}
-		query := req.URL.Query()
-		state := query.Get("state")
-		if state == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = io.WriteString(w, "no state")
-			return
-		}
-		if subtle.ConstantTimeCompare([]byte(callback.state), []byte(state)) == 0 {
-			w.WriteHeader(http.StatusForbidden)
-			_, _ = io.WriteString(w, "bad state")
-			return
-		}
-		code := query.Get("code")
-		if code == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			_, _ = io.WriteString(w, "no code")
-			return
-		}
-		callback.codes <- code
-	})
-}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    float64
+		wantErr bool
+	}{
+		{src: "", want: 0},
+		{src: "100", want: 100},
+		{src: "50,5", want: 50.5},
+		{src: "12.25", want: 12.25},
+		{src: "-", wantErr: true},
+		{src: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseScore(tt.src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseScore(%q): got error %v, want error %v", tt.src, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseScore(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestApiPagination(t *testing.T) {
+	tests := []struct {
+		numRows      int
+		pageSize     int
+		wantRequests int
+	}{
+		{numRows: 5, pageSize: 2, wantRequests: 3},
+		{numRows: 4, pageSize: 2, wantRequests: 3},
+		{numRows: 1, pageSize: 10, wantRequests: 1},
+		{numRows: 0, pageSize: 10, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("rows=%v,page=%v", tt.numRows, tt.pageSize), func(t *testing.T) {
+			env := newTestEnv(t, func(conf *Config) {
+				conf.PageSize = tt.pageSize
+			})
+			var logins []string
+			for i := 0; i < tt.numRows; i++ {
+				logins = append(logins, fmt.Sprintf("login-%v", i))
+			}
+			env.fake.SetStandings(1, fakeStandings(logins...))
+
+			st, err := env.api.FetchStandings(context.Background(), Contest{Type: ContestTypeYandex, ID: 1})
+			if err != nil {
+				t.Fatalf("fetching standings: %v", err)
+			}
+			got := participantLogins(st)
+			slices.Sort(got)
+			if !slices.Equal(got, logins) {
+				t.Errorf("got participants %v, want %v", got, logins)
+			}
+			if n := env.fake.Requests(); n != tt.wantRequests {
+				t.Errorf("got %v requests, want %v", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestApiPaginationLoginOrderChanged(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.PageSize = 2
+	})
+	// A participant moved from page 1 to page 2 between the requests.
+	env.fake.EnqueueResponses(1,
+		fakePage(fakeStandings("a", "b")),
+		fakePage(fakeStandings("b", "c")),
+	)
+	_, err := env.api.FetchStandings(context.Background(), Contest{Type: ContestTypeYandex, ID: 1})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `participant "b" appears both on page 1 and page 2`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestApiPaginationTitlesChanged(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.PageSize = 1
+	})
+	page2 := fakeStandings("b")
+	page2.Titles[0].Title = "A'"
+	env.fake.EnqueueResponses(1,
+		fakePage(fakeStandings("a")),
+		fakePage(page2),
+	)
+	_, err := env.api.FetchStandings(context.Background(), Contest{Type: ContestTypeYandex, ID: 1})
+	if err == nil || !strings.Contains(err.Error(), "different task titles") {
+		t.Errorf("got error %v, want task titles mismatch", err)
+	}
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const authAttemptTimeout = 15 * time.Minute
+
+type authAttempt struct {
+	verifier string
+	expires  time.Time
+}
+
+type AuthStatus struct {
+	Authorized bool
+	Expiry     time.Time
+	Err        error
+	ErrTime    time.Time
+}
+
+type Authorizer struct {
+	logger    *zap.Logger
+	oauthConf *oauth2.Config
+	tokens    *persistingTokenSource
+
+	mu           sync.Mutex
+	attempts     map[string]authAttempt
+	onAuthorized func()
+	err          error
+	errTime      time.Time
+}
+
+func newAuthorizer(logger *zap.Logger, oauthConf *oauth2.Config, tokens *persistingTokenSource) *Authorizer {
+	return &Authorizer{
+		logger:    logger,
+		oauthConf: oauthConf,
+		tokens:    tokens,
+		attempts:  make(map[string]authAttempt),
+	}
+}
+
+func genState() string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	var b strings.Builder
+	for i := 0; i < 24; i++ {
+		pos, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			panic(fmt.Errorf("cannot generate state: %w", err))
+		}
+		_ = b.WriteByte(letters[pos.Int64()])
+	}
+	return b.String()
+}
+
+func (a *Authorizer) OnAuthorized(f func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onAuthorized = f
+}
+
+func (a *Authorizer) Status() AuthStatus {
+	authorized, expiry := a.tokens.Status()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return AuthStatus{
+		Authorized: authorized,
+		Expiry:     expiry,
+		Err:        a.err,
+		ErrTime:    a.errTime,
+	}
+}
+
+func (a *Authorizer) Start() string {
+	state := genState()
+	verifier := oauth2.GenerateVerifier()
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for s, at := range a.attempts {
+		if now.After(at.expires) {
+			delete(a.attempts, s)
+		}
+	}
+	a.attempts[state] = authAttempt{
+		verifier: verifier,
+		expires:  now.Add(authAttemptTimeout),
+	}
+	a.logger.Info("started authorization attempt")
+	return a.oauthConf.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+}
+
+func (a *Authorizer) takeAttempt(state string) (authAttempt, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	at, ok := a.attempts[state]
+	if !ok {
+		return authAttempt{}, false
+	}
+	delete(a.attempts, state)
+	if time.Now().After(at.expires) {
+		return authAttempt{}, false
+	}
+	return at, true
+}
+
+func (a *Authorizer) setErr(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.err = err
+	if err != nil {
+		a.errTime = time.Now()
+	} else {
+		a.errTime = time.Time{}
+	}
+}
+
+func (a *Authorizer) finish(ctx context.Context, at authAttempt, code string) error {
+	tok, err := a.oauthConf.Exchange(ctx, code, oauth2.VerifierOption(at.verifier))
+	if err != nil {
+		return fmt.Errorf("cannot exchange code for token: %w", err)
+	}
+	tok.TokenType = "OAuth" // HACK
+	if err := a.tokens.SetToken(tok); err != nil {
+		return fmt.Errorf("storing token: %w", err)
+	}
+	return nil
+}
+
+func (a *Authorizer) ServeCallback(w http.ResponseWriter, req *http.Request) {
+	a.logger.Info("auth callback", zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "use GET method")
+		return
+	}
+	query := req.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, "no state")
+		return
+	}
+	at, ok := a.takeAttempt(state)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "bad or expired state, please try again")
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, "no code")
+		return
+	}
+	if err := a.finish(req.Context(), at, code); err != nil {
+		a.logger.Error("cannot finish authorization", zap.Error(err))
+		a.setErr(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, "got error: "+err.Error())
+		return
+	}
+	a.setErr(nil)
+	a.logger.Info("authorized in contest API")
+	a.mu.Lock()
+	onAuthorized := a.onAuthorized
+	a.mu.Unlock()
+	if onAuthorized != nil {
+		onAuthorized()
+	}
+	w.Header().Set("Location", "/admin/")
+	w.WriteHeader(http.StatusSeeOther)
+}
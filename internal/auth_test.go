@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+func newTestAuthorizer(t *testing.T) *Authorizer {
+	t.Helper()
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	t.Cleanup(tokenSrv.Close)
+	chdir(t, t.TempDir())
+	if err := os.Mkdir("secrets", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	oauthConf := &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://oauth.example.com/authorize",
+			TokenURL: tokenSrv.URL,
+		},
+	}
+	logger := zap.NewNop()
+	return newAuthorizer(logger, oauthConf, newPersistingTokenSource(logger, context.Background(), oauthConf, nil))
+}
+
+func startAuth(t *testing.T, a *Authorizer) string {
+	t.Helper()
+	u, err := url.Parse(a.Start())
+	if err != nil {
+		t.Fatalf("parsing auth url: %v", err)
+	}
+	state := u.Query().Get("state")
+	if state == "" {
+		t.Fatalf("no state in auth url %v", u)
+	}
+	return state
+}
+
+func TestAuthorizerCallback(t *testing.T) {
+	a := newTestAuthorizer(t)
+	authorized := false
+	a.OnAuthorized(func() { authorized = true })
+
+	w := serve(http.HandlerFunc(a.ServeCallback), "/authCallback?state=unknown&code=123")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("got status %v for unknown state, want %v", w.Code, http.StatusForbidden)
+	}
+
+	state := startAuth(t, a)
+	w = serve(http.HandlerFunc(a.ServeCallback), "/authCallback?state="+state+"&code=123")
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	if !authorized || !a.Status().Authorized {
+		t.Errorf("not authorized after the callback")
+	}
+
+	// The state can be used only once.
+	w = serve(http.HandlerFunc(a.ServeCallback), "/authCallback?state="+state+"&code=123")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("got status %v for reused state, want %v", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestAuthorizerStateExpiry(t *testing.T) {
+	a := newTestAuthorizer(t)
+	expired := startAuth(t, a)
+	a.mu.Lock()
+	at := a.attempts[expired]
+	at.expires = time.Now().Add(-time.Second)
+	a.attempts[expired] = at
+	a.mu.Unlock()
+
+	w := serve(http.HandlerFunc(a.ServeCallback), "/authCallback?state="+expired+"&code=123")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("got status %v for expired state, want %v", w.Code, http.StatusForbidden)
+	}
+	if a.Status().Authorized {
+		t.Errorf("authorized with expired state")
+	}
+
+	// Starting a new attempt drops the expired ones.
+	expired = startAuth(t, a)
+	a.mu.Lock()
+	at = a.attempts[expired]
+	at.expires = time.Now().Add(-time.Second)
+	a.attempts[expired] = at
+	a.mu.Unlock()
+	fresh := startAuth(t, a)
+	a.mu.Lock()
+	_, hasExpired := a.attempts[expired]
+	_, hasFresh := a.attempts[fresh]
+	a.mu.Unlock()
+	if hasExpired || !hasFresh {
+		t.Errorf("got expired attempt kept %v, fresh attempt kept %v, want false and true", hasExpired, hasFresh)
+	}
+}
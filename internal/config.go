@@ -5,14 +5,52 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 )
 
 type Contest struct {
-	ID  int    `json:"id"`
-	Tag string `json:"tag"`
+	Type   string   `json:"type"`
+	ID     int      `json:"id"`
+	Tag    string   `json:"tag"`
+	URL    string   `json:"url"`
+	Path   string   `json:"path"`
+	Weight *float64 `json:"weight"`
+	Group  string   `json:"group"`
+
+	MaxScore      *float64           `json:"max_score"`
+	TaskMaxScores map[string]float64 `json:"task_max_scores"`
+
+	FreezeTime *time.Time `json:"freeze_time"`
+}
+
+func (c Contest) TaskMaxScore(task string, def *float64) *float64 {
+	if v, ok := c.TaskMaxScores[task]; ok {
+		return &v
+	}
+	if c.MaxScore != nil {
+		return c.MaxScore
+	}
+	return def
+}
+
+func (c Contest) sourceKey() string {
+	return fmt.Sprintf("%v|%v|%v|%v", c.Type, c.ID, c.URL, c.Path)
+}
+
+func (c Contest) String() string {
+	if c.Tag != "" {
+		return c.Tag
+	}
+	if c.ID != 0 {
+		return fmt.Sprintf("contest %v", c.ID)
+	}
+	if c.Path != "" {
+		return c.Path
+	}
+	return c.URL
 }
 
 type TeamConfig struct {
@@ -21,23 +59,70 @@ type TeamConfig struct {
 	Logins   []string `json:"logins"`
 }
 
+type RetryConfig struct {
+	MaxAttempts int           `json:"max_attempts"`
+	BaseBackoff time.Duration `json:"base_backoff"`
+	MaxBackoff  time.Duration `json:"max_backoff"`
+	Jitter      *float64      `json:"jitter"`
+}
+
+func (c *RetryConfig) FillDefaults() {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 4
+	}
+	if c.BaseBackoff == 0 {
+		c.BaseBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = 10 * time.Second
+	}
+	if c.Jitter == nil {
+		jitter := 0.2
+		c.Jitter = &jitter
+	}
+}
+
+type AggregationConfig struct {
+	BestK         int               `json:"best_k"`
+	Normalization NormalizationMode `json:"normalization"`
+}
+
 type Config struct {
-	ListenAddr           string        `json:"listen_addr"`
-	SecureListenAddr     string        `json:"secure_listen_addr"`
-	AllowedSecureDomains []string      `json:"allowed_secure_domains"`
-	BaseURL              string        `json:"base_url"`
-	Contests             []Contest     `json:"contests"`
-	RefreshDuration      time.Duration `json:"refresh_duration"`
-	ErrorRefreshDuration time.Duration `json:"error_refresh_duration"`
-	StandingsForJudge    bool          `json:"standings_for_judge"`
-	PageSize             int           `json:"page_size"`
-	LoginWhitelistRegex  *string       `json:"login_whitelist_regex"`
-	LoginBlacklistRegex  *string       `json:"login_blacklist_regex"`
-	MaxScorePerTask      *float64      `json:"max_score_per_task"`
-	DisplayNames         bool          `json:"display_names"`
-	DisplayTeams         bool          `json:"display_teams"`
-	HideLogins           bool          `json:"hide_logins"`
-	Teams                []TeamConfig  `json:"teams"`
+	ListenAddr           string            `json:"listen_addr"`
+	SecureListenAddr     string            `json:"secure_listen_addr"`
+	AllowedSecureDomains []string          `json:"allowed_secure_domains"`
+	BaseURL              string            `json:"base_url"`
+	ApiBaseURL           string            `json:"api_base_url"`
+	ApiNoAuth            bool              `json:"api_no_auth"`
+	Contests             []Contest         `json:"contests"`
+	RefreshDuration      time.Duration     `json:"refresh_duration"`
+	ErrorRefreshDuration time.Duration     `json:"error_refresh_duration"`
+	StandingsForJudge    bool              `json:"standings_for_judge"`
+	PageSize             int               `json:"page_size"`
+	RequestTimeout       time.Duration     `json:"request_timeout"`
+	Retry                RetryConfig       `json:"retry"`
+	LoginWhitelistRegex  *string           `json:"login_whitelist_regex"`
+	LoginBlacklistRegex  *string           `json:"login_blacklist_regex"`
+	MaxScorePerTask      *float64          `json:"max_score_per_task"`
+	Ranking              RankingMode       `json:"ranking"`
+	PenaltyPerAttempt    *int              `json:"penalty_per_attempt"`
+	Aggregation          AggregationConfig `json:"aggregation"`
+	DisplayNames         bool              `json:"display_names"`
+	DisplayTeams         bool              `json:"display_teams"`
+	HideLogins           bool              `json:"hide_logins"`
+	Teams                []TeamConfig      `json:"teams"`
+	TeamRanking          TeamRanking       `json:"team_ranking"`
+	Aliases              map[string]string `json:"aliases"`
+	MergeByName          bool              `json:"merge_by_name"`
+}
+
+func (c *Config) UsesYandexApi() bool {
+	for _, ct := range c.Contests {
+		if ct.Type == ContestTypeYandex {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Config) FillDefaults() {
@@ -47,20 +132,45 @@ func (c *Config) FillDefaults() {
 	if c.BaseURL == "" {
 		c.BaseURL = "http://localhost:8080"
 	}
+	if c.ApiBaseURL == "" {
+		c.ApiBaseURL = "https://api.contest.yandex.net"
+	}
+	c.ApiBaseURL = strings.TrimSuffix(c.ApiBaseURL, "/")
 	if c.RefreshDuration == 0 {
 		c.RefreshDuration = 60 * time.Second
 	}
 	if c.ErrorRefreshDuration == 0 {
 		c.ErrorRefreshDuration = 1 * time.Second
 	}
+	for i := range c.Contests {
+		if c.Contests[i].Type == "" {
+			c.Contests[i].Type = ContestTypeYandex
+		}
+	}
 	if c.PageSize == 0 {
 		c.PageSize = 10000
 	}
+	if c.Ranking == "" {
+		c.Ranking = RankingModeScore
+	}
+	if c.PenaltyPerAttempt == nil {
+		penalty := 20
+		c.PenaltyPerAttempt = &penalty
+	}
+	if c.RequestTimeout == 0 {
+		c.RequestTimeout = 30 * time.Second
+	}
+	if c.TeamRanking.Mode == "" {
+		c.TeamRanking.Mode = TeamScoreModeSum
+	}
+	c.Retry.FillDefaults()
 }
 
 type StaticSecrets struct {
-	ClientID     string `json:"client_id"`
-	ClientSecret string `json:"client_secret"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	JuryPassword  string `json:"jury_password"`
+	AdminPassword string `json:"admin_password"`
 }
 
 type DynamicSecrets struct {
@@ -84,14 +194,21 @@ func unmarshalFromFile(name string, value any) error {
 	return nil
 }
 
-func LoadConfig() (*Config, error) {
-	var c Config
-	err := unmarshalFromFile("config.json", &c)
+// LoadConfigFrom reads the config from the given file. Unlike the secrets, the config file must exist.
+func LoadConfigFrom(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("validating config: %w", err)
 	}
-	c.FillDefaults()
-	return &c, nil
+	return c, nil
+}
+
+func LoadConfig() (*Config, error) {
+	return LoadConfigFrom("config.json")
 }
 
 func LoadStaticSecrets() (*StaticSecrets, error) {
@@ -113,15 +230,27 @@ func LoadDynamicSecrets() (*DynamicSecrets, error) {
 }
 
 func StoreDynamicSecrets(s *DynamicSecrets) error {
-	f, err := os.Create("secrets/dynamic.json")
+	f, err := os.CreateTemp("secrets", "dynamic-*.json")
 	if err != nil {
 		return fmt.Errorf("storing dynamic secrets: %w", err)
 	}
-	defer f.Close()
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
 	e := json.NewEncoder(f)
 	err = e.Encode(s)
 	if err != nil {
 		return fmt.Errorf("encoding json: %w", err)
 	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+	if err := os.Rename(f.Name(), "secrets/dynamic.json"); err != nil {
+		return fmt.Errorf("renaming file: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,482 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alex65536/yacontable/pkg/goutil"
+)
+
+// ConfigError is a single problem in the config. Path is the JSON path of the offending value,
+// like contests[1].tag, or empty if the problem is not related to a particular value.
+type ConfigError struct {
+	Path string
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	return strings.Join(goutil.Map(e, func(e *ConfigError) string {
+		return e.Error()
+	}), "\n")
+}
+
+func (e *ConfigErrors) add(path string, format string, args ...any) {
+	*e = append(*e, &ConfigError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (e *ConfigErrors) addNested(parent string, errs ConfigErrors) {
+	for _, err := range errs {
+		e.add(fieldPath(parent, err.Path), "%v", err.Msg)
+	}
+}
+
+func (e ConfigErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func fieldPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "." + name
+}
+
+func indexPath(parent string, i int) string {
+	return fmt.Sprintf("%v[%v]", parent, i)
+}
+
+func keyPath(parent string, key string) string {
+	return fmt.Sprintf("%v[%q]", parent, key)
+}
+
+func textPosition(data []byte, offset int64) (line, col int) {
+	data = data[:min(int(offset), len(data))]
+	line = bytes.Count(data, []byte("\n")) + 1
+	col = len(data) - bytes.LastIndexByte(data, '\n')
+	return line, col
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	res := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		res[name] = f
+	}
+	return res
+}
+
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if f, ok := fields[key]; ok {
+		return f, true
+	}
+	// encoding/json matches the keys case-insensitively, so do we.
+	for name, f := range fields {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const unknownFieldMsg = "unknown field"
+
+// checkJSONShape reports the unknown fields and the values of wrong type in v, which is decoded
+// as a JSON value of type t. Unlike encoding/json, it reports all the problems, not only the first one.
+func checkJSONShape(errs *ConfigErrors, path string, v any, t reflect.Type) {
+	if v == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("cannot marshal decoded json: %v", err))
+		}
+		if err := json.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+			errs.add(path, "%v", err)
+		}
+		return
+	}
+	mismatch := func(want string) {
+		errs.add(path, "expected %v, got %v", want, jsonKind(v))
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			mismatch("object")
+			return
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(obj) {
+			f, ok := lookupJSONField(fields, key)
+			if !ok {
+				errs.add(fieldPath(path, key), unknownFieldMsg)
+				continue
+			}
+			checkJSONShape(errs, fieldPath(path, key), obj[key], f.Type)
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			mismatch("object")
+			return
+		}
+		for _, key := range sortedKeys(obj) {
+			checkJSONShape(errs, keyPath(path, key), obj[key], t.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.([]any)
+		if !ok {
+			mismatch("array")
+			return
+		}
+		for i, item := range arr {
+			checkJSONShape(errs, indexPath(path, i), item, t.Elem())
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, ok := v.(float64)
+		if !ok || num != math.Trunc(num) {
+			mismatch("integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			mismatch("number")
+		}
+	}
+}
+
+func jsonKind(v any) string {
+	switch v := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return fmt.Sprintf("number %v", v)
+	default:
+		return "null"
+	}
+}
+
+// ParseConfig decodes the config strictly, fills the defaults and validates it. On failure, it
+// returns ConfigErrors with all the problems found.
+func ParseConfig(data []byte) (*Config, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			line, col := textPosition(data, serr.Offset)
+			return nil, ConfigErrors{{Msg: fmt.Sprintf("line %v, column %v: %v", line, col, serr)}}
+		}
+		return nil, fmt.Errorf("unmarshalling json: %w", err)
+	}
+	var errs ConfigErrors
+	if _, ok := raw.(map[string]any); !ok {
+		errs.add("", "config must be a JSON object, got %v", jsonKind(raw))
+		return nil, errs
+	}
+	checkJSONShape(&errs, "", raw, reflect.TypeOf(Config{}))
+	// The unknown fields are skipped by encoding/json, so the config is still validated and all the problems
+	// are reported at once. With values of wrong type, the decoded config is incomplete and is not validated.
+	if slices.ContainsFunc(errs, func(e *ConfigError) bool { return e.Msg != unknownFieldMsg }) {
+		return nil, errs
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unmarshalling json: %w", err)
+	}
+	c.FillDefaults()
+	if err := c.Validate(); err != nil {
+		var verrs ConfigErrors
+		if !errors.As(err, &verrs) {
+			return nil, err
+		}
+		errs = append(errs, verrs...)
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return &c, nil
+}
+
+func checkListenAddr(errs *ConfigErrors, path string, addr string) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		errs.add(path, "bad address %q: %v", addr, err)
+	}
+}
+
+func checkHTTPURL(errs *ConfigErrors, path string, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		errs.add(path, "%v", err)
+		return nil
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		errs.add(path, "%q must start with http:// or https://", rawURL)
+		return nil
+	}
+	if u.Host == "" {
+		errs.add(path, "%q has no host", rawURL)
+		return nil
+	}
+	return u
+}
+
+func checkRegex(errs *ConfigErrors, path string, re string) {
+	if _, err := regexp.Compile(re); err != nil {
+		errs.add(path, "%v", err)
+	}
+}
+
+func checkPositive[T ~int | ~int64 | ~float64](errs *ConfigErrors, path string, v T) {
+	if v <= 0 {
+		errs.add(path, "must be positive, got %v", v)
+	}
+}
+
+func checkNonNegative[T ~int | ~int64 | ~float64](errs *ConfigErrors, path string, v T) {
+	if v < 0 {
+		errs.add(path, "must be non-negative, got %v", v)
+	}
+}
+
+func contestSourceProblems(ct Contest) ConfigErrors {
+	var errs ConfigErrors
+	switch ct.Type {
+	case ContestTypeYandex:
+		if ct.ID <= 0 {
+			errs.add("id", "contest id is not specified")
+		}
+	case ContestTypeURL:
+		if ct.URL == "" {
+			errs.add("url", "url is not specified")
+		} else {
+			checkHTTPURL(&errs, "url", ct.URL)
+		}
+	case ContestTypeFile:
+		if ct.Path == "" {
+			errs.add("path", "path is not specified")
+		}
+	default:
+		errs.add("type", "unknown contest type %q", ct.Type)
+	}
+	return errs
+}
+
+func contestProblems(ct Contest) ConfigErrors {
+	errs := contestSourceProblems(ct)
+	if ct.Weight != nil {
+		checkNonNegative(&errs, "weight", *ct.Weight)
+	}
+	if ct.MaxScore != nil {
+		checkPositive(&errs, "max_score", *ct.MaxScore)
+	}
+	tasks := make([]string, 0, len(ct.TaskMaxScores))
+	for task := range ct.TaskMaxScores {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+	for _, task := range tasks {
+		checkPositive(&errs, keyPath("task_max_scores", task), ct.TaskMaxScores[task])
+	}
+	return errs
+}
+
+func validateContest(ct Contest) error {
+	return contestProblems(ct).Err()
+}
+
+func contestSourceField(ct Contest) string {
+	switch ct.Type {
+	case ContestTypeURL:
+		return "url"
+	case ContestTypeFile:
+		return "path"
+	default:
+		return "id"
+	}
+}
+
+func (c *Config) validateContests(errs *ConfigErrors) {
+	sources := make(map[string]int)
+	tags := make(map[string]int)
+	for i, ct := range c.Contests {
+		path := indexPath("contests", i)
+		errs.addNested(path, contestProblems(ct))
+		if len(contestSourceProblems(ct)) == 0 {
+			if j, ok := sources[ct.sourceKey()]; ok {
+				errs.add(fieldPath(path, contestSourceField(ct)), "same contest as contests[%v]", j)
+			} else {
+				sources[ct.sourceKey()] = i
+			}
+		}
+		if ct.Tag != "" {
+			if j, ok := tags[ct.Tag]; ok {
+				errs.add(fieldPath(path, "tag"), "tag %q is already used by contests[%v]", ct.Tag, j)
+			} else {
+				tags[ct.Tag] = i
+			}
+		}
+		// Tasks are not known until the contest is fetched, so require a max score that covers all of them.
+		if c.Aggregation.Normalization == NormalizationMax && c.MaxScorePerTask == nil && ct.MaxScore == nil {
+			errs.add(fieldPath(path, "max_score"), "must be set when normalization is %q and max_score_per_task is not set", NormalizationMax)
+		}
+	}
+}
+
+func (c *Config) validateTeams(errs *ConfigErrors) {
+	logins := make(map[string]int)
+	for i, team := range c.Teams {
+		path := indexPath("teams", i)
+		for j, pattern := range team.Patterns {
+			checkRegex(errs, indexPath(fieldPath(path, "patterns"), j), pattern)
+		}
+		for j, login := range team.Logins {
+			if k, ok := logins[login]; ok {
+				errs.add(indexPath(fieldPath(path, "logins"), j), "login %q is already in teams[%v]", login, k)
+			} else {
+				logins[login] = i
+			}
+		}
+	}
+	if err := c.TeamRanking.Validate(); err != nil {
+		path := "team_ranking.mode"
+		if c.TeamRanking.Mode == TeamScoreModeBestN {
+			path = "team_ranking.best_n"
+		}
+		errs.add(path, "%v", err)
+	}
+}
+
+func (c *Config) validateServer(errs *ConfigErrors) {
+	checkListenAddr(errs, "listen_addr", c.ListenAddr)
+	if c.SecureListenAddr != "" {
+		checkListenAddr(errs, "secure_listen_addr", c.SecureListenAddr)
+		if len(c.AllowedSecureDomains) == 0 {
+			errs.add("allowed_secure_domains", "must not be empty when secure_listen_addr is set")
+		}
+	} else if len(c.AllowedSecureDomains) != 0 {
+		errs.add("allowed_secure_domains", "secure_listen_addr is not set, so the domains are not used")
+	}
+	for i, domain := range c.AllowedSecureDomains {
+		if domain == "" || strings.ContainsAny(domain, ":/ ") {
+			errs.add(indexPath("allowed_secure_domains", i), "%q is not a domain name", domain)
+		}
+	}
+	if u := checkHTTPURL(errs, "base_url", c.BaseURL); u != nil {
+		if c.SecureListenAddr != "" && u.Scheme == "https" && !slices.Contains(c.AllowedSecureDomains, u.Hostname()) {
+			errs.add("base_url", "host %q is not in allowed_secure_domains", u.Hostname())
+		}
+	}
+	checkHTTPURL(errs, "api_base_url", c.ApiBaseURL)
+}
+
+// Validate checks the config after FillDefaults. It returns ConfigErrors with all the problems found.
+func (c *Config) Validate() error {
+	var errs ConfigErrors
+	c.validateServer(&errs)
+
+	checkPositive(&errs, "refresh_duration", c.RefreshDuration)
+	checkPositive(&errs, "error_refresh_duration", c.ErrorRefreshDuration)
+	checkPositive(&errs, "request_timeout", c.RequestTimeout)
+	checkPositive(&errs, "page_size", c.PageSize)
+	checkPositive(&errs, "retry.max_attempts", c.Retry.MaxAttempts)
+	checkPositive(&errs, "retry.base_backoff", c.Retry.BaseBackoff)
+	if c.Retry.MaxBackoff < c.Retry.BaseBackoff {
+		errs.add("retry.max_backoff", "must not be less than base_backoff")
+	}
+	if c.Retry.Jitter != nil && (*c.Retry.Jitter < 0 || *c.Retry.Jitter > 1) {
+		errs.add("retry.jitter", "must be between 0 and 1, got %v", *c.Retry.Jitter)
+	}
+
+	if c.LoginWhitelistRegex != nil {
+		checkRegex(&errs, "login_whitelist_regex", *c.LoginWhitelistRegex)
+	}
+	if c.LoginBlacklistRegex != nil {
+		checkRegex(&errs, "login_blacklist_regex", *c.LoginBlacklistRegex)
+	}
+	if c.MaxScorePerTask != nil {
+		checkPositive(&errs, "max_score_per_task", *c.MaxScorePerTask)
+	}
+
+	switch c.Ranking {
+	case RankingModeScore, RankingModeICPC:
+	default:
+		errs.add("ranking", "unknown ranking mode %q", c.Ranking)
+	}
+	if c.PenaltyPerAttempt != nil {
+		checkNonNegative(&errs, "penalty_per_attempt", *c.PenaltyPerAttempt)
+	}
+	switch c.Aggregation.Normalization {
+	case NormalizationNone, NormalizationMax, NormalizationBest, NormalizationZScore:
+	default:
+		errs.add("aggregation.normalization", "unknown normalization mode %q", c.Aggregation.Normalization)
+	}
+	checkNonNegative(&errs, "aggregation.best_k", c.Aggregation.BestK)
+
+	c.validateTeams(&errs)
+	c.validateContests(&errs)
+	return errs.Err()
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "valid",
+			src:  `{"contests": [{"id": 1, "tag": "D1"}, {"type": "file", "path": "d2.json"}]}`,
+		},
+		{
+			name: "syntax error",
+			src:  "{\n  \"contests\": [\n    {\"id\": 1,}\n  ]\n}",
+			want: []string{"line 3, column 15: invalid character '}' looking for beginning of object key string"},
+		},
+		{
+			name: "not an object",
+			src:  `[]`,
+			want: []string{"config must be a JSON object, got array"},
+		},
+		{
+			name: "unknown fields and wrong types",
+			src:  `{"contest": [], "page_size": "10", "contests": [{"id": 1.5}, {"id": 2, "weigth": 1}], "aliases": {"a": 1}}`,
+			want: []string{
+				`aliases["a"]: expected string, got number 1`,
+				"contest: unknown field",
+				"contests[0].id: expected integer, got number 1.5",
+				"contests[1].weigth: unknown field",
+				"page_size: expected integer, got string",
+			},
+		},
+		{
+			name: "unknown fields and bad values",
+			src:  `{"contests": [{"id": 1, "tag": "D1", "weigth": 1}, {"id": 2, "tag": "D1"}], "page_sise": 10}`,
+			want: []string{
+				"contests[0].weigth: unknown field",
+				"page_sise: unknown field",
+				`contests[1].tag: tag "D1" is already used by contests[0]`,
+			},
+		},
+		{
+			name: "bad contests",
+			src: `{"contests": [
+				{"id": 1, "tag": "D1"},
+				{"id": 1, "tag": "D1"},
+				{"type": "url", "url": "ftp://example.com"},
+				{"type": "ftp"},
+				{"id": 0, "max_score": -1, "task_max_scores": {"A": 0}}
+			]}`,
+			want: []string{
+				"contests[1].id: same contest as contests[0]",
+				`contests[1].tag: tag "D1" is already used by contests[0]`,
+				`contests[2].url: "ftp://example.com" must start with http:// or https://`,
+				`contests[3].type: unknown contest type "ftp"`,
+				"contests[4].id: contest id is not specified",
+				"contests[4].max_score: must be positive, got -1",
+				`contests[4].task_max_scores["A"]: must be positive, got 0`,
+			},
+		},
+		{
+			name: "bad regex and teams",
+			src: `{"contests": [{"id": 1}], "login_whitelist_regex": "(", "teams": [
+				{"name": "a", "patterns": ["["], "logins": ["x"]},
+				{"name": "b", "logins": ["x"]}
+			]}`,
+			want: []string{
+				"login_whitelist_regex: error parsing regexp: missing closing ): `(`",
+				"teams[0].patterns[0]: error parsing regexp: missing closing ]: `[`",
+				`teams[1].logins[0]: login "x" is already in teams[0]`,
+			},
+		},
+		{
+			name: "secure domains without secure address",
+			src:  `{"contests": [{"id": 1}], "allowed_secure_domains": ["example.com"]}`,
+			want: []string{"allowed_secure_domains: secure_listen_addr is not set, so the domains are not used"},
+		},
+		{
+			name: "secure address without domains",
+			src:  `{"contests": [{"id": 1}], "secure_listen_addr": "0.0.0.0:443", "base_url": "https://example.com"}`,
+			want: []string{
+				"allowed_secure_domains: must not be empty when secure_listen_addr is set",
+				`base_url: host "example.com" is not in allowed_secure_domains`,
+			},
+		},
+		{
+			name: "bad addresses and urls",
+			src:  `{"contests": [{"id": 1}], "listen_addr": "8080", "base_url": "localhost:8080", "api_base_url": "https:///api"}`,
+			want: []string{
+				`listen_addr: bad address "8080": address 8080: missing port in address`,
+				`base_url: "localhost:8080" must start with http:// or https://`,
+				`api_base_url: "https:///api" has no host`,
+			},
+		},
+		{
+			name: "bad numbers",
+			src:  `{"contests": [{"id": 1}], "page_size": -1, "retry": {"jitter": 2}, "aggregation": {"best_k": -1, "normalization": "median"}}`,
+			want: []string{
+				"page_size: must be positive, got -1",
+				"retry.jitter: must be between 0 and 1, got 2",
+				`aggregation.normalization: unknown normalization mode "median"`,
+				"aggregation.best_k: must be non-negative, got -1",
+			},
+		},
+		{
+			name: "max normalization without max scores",
+			src:  `{"contests": [{"id": 1, "max_score": 100}, {"id": 2}], "aggregation": {"normalization": "max"}}`,
+			want: []string{`contests[1].max_score: must be set when normalization is "max" and max_score_per_task is not set`},
+		},
+		{
+			name: "max normalization with max score per task",
+			src:  `{"contests": [{"id": 1}, {"id": 2}], "max_score_per_task": 100, "aggregation": {"normalization": "max"}}`,
+		},
+	}
+	for _, tt := range tests {
+		conf, err := ParseConfig([]byte(tt.src))
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%v: unexpected error: %v", tt.name, err)
+			} else if conf == nil {
+				t.Errorf("%v: got nil config", tt.name)
+			}
+			continue
+		}
+		var errs ConfigErrors
+		if !errors.As(err, &errs) {
+			t.Errorf("%v: got error %v, want ConfigErrors", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, e := range errs {
+			got = append(got, e.Error())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: got errors\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfigDefaults(t *testing.T) {
+	conf, err := ParseConfig([]byte(`{"contests": [{"id": 1}], "api_base_url": "http://localhost:1234/"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Contests[0].Type != ContestTypeYandex {
+		t.Errorf("got contest type %q, want %q", conf.Contests[0].Type, ContestTypeYandex)
+	}
+	if conf.ApiBaseURL != "http://localhost:1234" {
+		t.Errorf("got api base url %q, want trailing slash removed", conf.ApiBaseURL)
+	}
+	if conf.PageSize != 10000 || conf.Ranking != RankingModeScore {
+		t.Errorf("got page size %v and ranking %q, want defaults", conf.PageSize, conf.Ranking)
+	}
+	if *conf.PenaltyPerAttempt != 20 {
+		t.Errorf("got penalty per attempt %v, want 20", *conf.PenaltyPerAttempt)
+	}
+
+	// Zero is a valid value, not a missing one.
+	conf, err = ParseConfig([]byte(`{"contests": [{"id": 1}], "penalty_per_attempt": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *conf.PenaltyPerAttempt != 0 {
+		t.Errorf("got penalty per attempt %v, want 0", *conf.PenaltyPerAttempt)
+	}
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+type ConfigStore struct {
+	mu   sync.Mutex
+	conf atomic.Pointer[Config]
+	subs []func(conf *Config)
+}
+
+func NewConfigStore(conf *Config) *ConfigStore {
+	s := &ConfigStore{}
+	s.conf.Store(conf)
+	return s
+}
+
+func (c *Config) Clone() *Config {
+	res := *c
+	res.AllowedSecureDomains = slices.Clone(c.AllowedSecureDomains)
+	res.Contests = slices.Clone(c.Contests)
+	res.Teams = slices.Clone(c.Teams)
+	res.Aliases = maps.Clone(c.Aliases)
+	return &res
+}
+
+func (s *ConfigStore) Get() *Config {
+	return s.conf.Load()
+}
+
+func (s *ConfigStore) Subscribe(f func(conf *Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs = append(s.subs, f)
+}
+
+// Swap atomically replaces the config with the one returned by f, which gets the current config. Unlike Update,
+// f may build the new config from scratch, and it is responsible for validating it.
+func (s *ConfigStore) Swap(f func(cur *Config) (*Config, error)) error {
+	s.mu.Lock()
+	conf, err := f(s.conf.Load())
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.conf.Store(conf)
+	subs := slices.Clone(s.subs)
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub(conf)
+	}
+	return nil
+}
+
+// Update applies f to a copy of the current config, validates the result and atomically replaces the config with it.
+// Configs are never mutated in place, so readers may keep using the config they got from Get.
+func (s *ConfigStore) Update(f func(conf *Config) error) error {
+	return s.Swap(func(cur *Config) (*Config, error) {
+		conf := cur.Clone()
+		if err := f(conf); err != nil {
+			return nil, err
+		}
+		conf.FillDefaults()
+		if err := conf.Validate(); err != nil {
+			return nil, fmt.Errorf("validating config: %w", err)
+		}
+		return conf, nil
+	})
+}
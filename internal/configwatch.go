@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const configPollInterval = 2 * time.Second
+
+type ConfigReloader struct {
+	logger *zap.Logger
+	store  *ConfigStore
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	base    *Config // last config loaded from the file, without the runtime changes
+}
+
+func NewConfigReloader(logger *zap.Logger, store *ConfigStore) *ConfigReloader {
+	r := &ConfigReloader{
+		logger: logger,
+		store:  store,
+		base:   store.Get(),
+	}
+	if fi, err := os.Stat("config.json"); err == nil {
+		r.modTime = fi.ModTime()
+		r.size = fi.Size()
+	}
+	return r
+}
+
+func restartOnlyChanges(old, conf *Config) []string {
+	var res []string
+	if old.ListenAddr != conf.ListenAddr {
+		res = append(res, "listen_addr")
+	}
+	if old.SecureListenAddr != conf.SecureListenAddr {
+		res = append(res, "secure_listen_addr")
+	}
+	if !slices.Equal(old.AllowedSecureDomains, conf.AllowedSecureDomains) {
+		res = append(res, "allowed_secure_domains")
+	}
+	if old.BaseURL != conf.BaseURL {
+		res = append(res, "base_url")
+	}
+	if old.ApiNoAuth != conf.ApiNoAuth {
+		res = append(res, "api_no_auth")
+	}
+	return res
+}
+
+// applyRuntimeChanges re-applies the changes made at runtime (in the admin panel) on top of the reloaded config.
+// The changes are found by comparing the current config with the one previously loaded from the file. If the file
+// changes the same setting, the file wins. It returns the descriptions of the changes kept.
+func applyRuntimeChanges(base, cur, conf *Config) []string {
+	var res []string
+	flags := []struct {
+		name            string
+		base, cur, file *bool
+	}{
+		{"hide_logins", &base.HideLogins, &cur.HideLogins, &conf.HideLogins},
+		{"display_names", &base.DisplayNames, &cur.DisplayNames, &conf.DisplayNames},
+		{"display_teams", &base.DisplayTeams, &cur.DisplayTeams, &conf.DisplayTeams},
+	}
+	for _, f := range flags {
+		if *f.cur != *f.base && *f.file == *f.base {
+			*f.file = *f.cur
+			res = append(res, fmt.Sprintf("%v=%v", f.name, *f.cur))
+		}
+	}
+
+	hasContest := func(c *Config, ct Contest) bool {
+		return slices.ContainsFunc(c.Contests, func(other Contest) bool {
+			return other.sourceKey() == ct.sourceKey()
+		})
+	}
+	for _, ct := range cur.Contests {
+		if !hasContest(base, ct) && !hasContest(conf, ct) {
+			conf.Contests = append(conf.Contests, ct)
+			res = append(res, fmt.Sprintf("added contest %v", ct))
+		}
+	}
+	for _, ct := range base.Contests {
+		if !hasContest(cur, ct) && hasContest(conf, ct) {
+			conf.Contests = slices.DeleteFunc(conf.Contests, func(other Contest) bool {
+				return other.sourceKey() == ct.sourceKey()
+			})
+			res = append(res, fmt.Sprintf("removed contest %v", ct))
+		}
+	}
+	return res
+}
+
+func (r *ConfigReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fi, err := os.Stat("config.json")
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	r.modTime = fi.ModTime()
+	r.size = fi.Size()
+	conf, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	// Merge under the store lock, so the admin panel changes made meanwhile are not lost.
+	err = r.store.Swap(func(cur *Config) (*Config, error) {
+		if fields := restartOnlyChanges(cur, conf); len(fields) != 0 {
+			r.logger.Warn("some config changes require restart", zap.Strings("fields", fields))
+		}
+		merged := conf.Clone()
+		if changes := applyRuntimeChanges(r.base, cur, merged); len(changes) != 0 {
+			if err := merged.Validate(); err != nil {
+				r.logger.Warn("runtime changes made in the admin panel conflict with the new config, dropping them",
+					zap.Strings("changes", changes), zap.Error(err))
+				merged = conf
+			} else {
+				r.logger.Warn("keeping runtime changes made in the admin panel, they are not saved to config file",
+					zap.Strings("changes", changes))
+			}
+		}
+		return merged, nil
+	})
+	if err != nil {
+		return err
+	}
+	r.base = conf
+	r.logger.Info("config reloaded")
+	return nil
+}
+
+func (r *ConfigReloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fi, err := os.Stat("config.json")
+	if err != nil {
+		return false
+	}
+	return !fi.ModTime().Equal(r.modTime) || fi.Size() != r.size
+}
+
+func (r *ConfigReloader) Watch(ctx context.Context) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !r.changed() {
+			continue
+		}
+		r.logger.Info("config file changed, reloading")
+		if err := r.Reload(); err != nil {
+			r.logger.Error("cannot reload config, keeping the old one", zap.Error(err))
+		}
+	}
+}
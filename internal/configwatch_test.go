@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestApplyRuntimeChanges(t *testing.T) {
+	d1 := Contest{Type: ContestTypeYandex, ID: 1}
+	d2 := Contest{Type: ContestTypeYandex, ID: 2}
+	d3 := Contest{Type: ContestTypeYandex, ID: 3}
+	tests := []struct {
+		name         string
+		base, cur    Config
+		file         Config
+		want         Config
+		wantChanges  []string
+		wantContests []int
+	}{
+		{
+			name:         "no runtime changes",
+			base:         Config{Contests: []Contest{d1}},
+			cur:          Config{Contests: []Contest{d1}},
+			file:         Config{Contests: []Contest{d1, d2}, HideLogins: true},
+			want:         Config{HideLogins: true},
+			wantContests: []int{1, 2},
+		},
+		{
+			name:         "display flags are kept",
+			base:         Config{Contests: []Contest{d1}},
+			cur:          Config{Contests: []Contest{d1}, HideLogins: true, DisplayNames: true},
+			file:         Config{Contests: []Contest{d1}},
+			want:         Config{HideLogins: true, DisplayNames: true},
+			wantChanges:  []string{"hide_logins=true", "display_names=true"},
+			wantContests: []int{1},
+		},
+		{
+			name:         "flag changed in the file too",
+			base:         Config{Contests: []Contest{d1}, HideLogins: true},
+			cur:          Config{Contests: []Contest{d1}},
+			file:         Config{Contests: []Contest{d1}, DisplayNames: true},
+			want:         Config{DisplayNames: true},
+			wantContests: []int{1},
+		},
+		{
+			name:         "added and removed contests are kept",
+			base:         Config{Contests: []Contest{d1, d2}},
+			cur:          Config{Contests: []Contest{d2, d3}},
+			file:         Config{Contests: []Contest{d1, d2}},
+			wantChanges:  []string{"added contest contest 3", "removed contest contest 1"},
+			wantContests: []int{2, 3},
+		},
+		{
+			name:         "contest added to the file too",
+			base:         Config{Contests: []Contest{d1}},
+			cur:          Config{Contests: []Contest{d1, d3}},
+			file:         Config{Contests: []Contest{d1, d3}},
+			wantContests: []int{1, 3},
+		},
+		{
+			name:         "contest removed from the file too",
+			base:         Config{Contests: []Contest{d1, d2}},
+			cur:          Config{Contests: []Contest{d1}},
+			file:         Config{Contests: []Contest{d1}},
+			wantContests: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		conf := tt.file.Clone()
+		changes := applyRuntimeChanges(&tt.base, &tt.cur, conf)
+		if !slices.Equal(changes, tt.wantChanges) {
+			t.Errorf("%v: got changes %q, want %q", tt.name, changes, tt.wantChanges)
+		}
+		ids := make([]int, len(conf.Contests))
+		for i, ct := range conf.Contests {
+			ids[i] = ct.ID
+		}
+		if !slices.Equal(ids, tt.wantContests) {
+			t.Errorf("%v: got contests %v, want %v", tt.name, ids, tt.wantContests)
+		}
+		if conf.HideLogins != tt.want.HideLogins || conf.DisplayNames != tt.want.DisplayNames || conf.DisplayTeams != tt.want.DisplayTeams {
+			t.Errorf("%v: got hide_logins=%v, display_names=%v, display_teams=%v, want %v, %v, %v", tt.name,
+				conf.HideLogins, conf.DisplayNames, conf.DisplayTeams, tt.want.HideLogins, tt.want.DisplayNames, tt.want.DisplayTeams)
+		}
+	}
+}
+
+func TestConfigReloader(t *testing.T) {
+	chdir(t, t.TempDir())
+	writeConfig := func(data string) {
+		if err := os.WriteFile("config.json", []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`{"contests": [{"id": 1}, {"id": 2}]}`)
+	conf, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewConfigStore(conf)
+	r := NewConfigReloader(zap.NewNop(), store)
+
+	err = store.Update(func(conf *Config) error {
+		conf.HideLogins = true
+		conf.Contests = append(conf.Contests, Contest{ID: 3})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig(`{"contests": [{"id": 1}, {"id": 2}], "refresh_duration": 5000000000}`)
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	got := store.Get()
+	if got.RefreshDuration != 5*time.Second || !got.HideLogins || len(got.Contests) != 3 {
+		t.Errorf("got refresh_duration=%v, hide_logins=%v, %v contests, want 5s, true and 3",
+			got.RefreshDuration, got.HideLogins, len(got.Contests))
+	}
+
+	// The tag conflicts with the contest added in the admin panel, so the runtime changes are dropped.
+	writeConfig(`{"contests": [{"id": 1, "tag": "X"}, {"id": 2}], "hide_logins": false, "aggregation": {"best_k": 1}}`)
+	err = store.Update(func(conf *Config) error {
+		conf.Contests[2].Tag = "X"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	got = store.Get()
+	if got.Aggregation.BestK != 1 || got.HideLogins || len(got.Contests) != 2 {
+		t.Errorf("got best_k=%v, hide_logins=%v, %v contests, want 1, false and 2",
+			got.Aggregation.BestK, got.HideLogins, len(got.Contests))
+	}
+
+	writeConfig(`{"contests": [{"id": 1}], "page_size": -1}`)
+	if err := r.Reload(); err == nil {
+		t.Error("invalid config is reloaded")
+	}
+	if store.Get() != got {
+		t.Error("invalid config replaced the current one")
+	}
+}
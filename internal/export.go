@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+type ExportFormat struct {
+	Ext         string
+	ContentType string
+	Comma       rune
+}
+
+var (
+	ExportFormatCSV = ExportFormat{
+		Ext:         "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Comma:       ',',
+	}
+	ExportFormatTSV = ExportFormat{
+		Ext:         "tsv",
+		ContentType: "text/tab-separated-values; charset=utf-8",
+		Comma:       '\t',
+	}
+)
+
+const utf8BOM = "\xef\xbb\xbf"
+
+func formatExportScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+func exportInfoColumns(conf *Config) int {
+	res := 1
+	if !conf.HideLogins {
+		res++
+	}
+	if conf.DisplayNames {
+		res++
+	}
+	if conf.DisplayTeams {
+		res++
+	}
+	return res
+}
+
+func exportHeader(conf *Config, st *Standings) []string {
+	res := []string{"Rank"}
+	if !conf.HideLogins {
+		res = append(res, "Login")
+	}
+	if conf.DisplayNames {
+		res = append(res, "Name")
+	}
+	if conf.DisplayTeams {
+		res = append(res, "Team")
+	}
+	for _, t := range st.Header.Tasks {
+		res = append(res, t.Title)
+	}
+	if st.IsICPC() {
+		return append(res, "Solved", "Penalty")
+	}
+	if st.IsNormalized() {
+		return append(res, "Total", "Raw total")
+	}
+	return append(res, "Total")
+}
+
+func formatExportCell(c ParticipantCell) string {
+	if c.Accepted {
+		return fmt.Sprintf("%v %v", c.Verdict(), c.FormatTime())
+	}
+	return c.Verdict()
+}
+
+func exportParticipant(conf *Config, st *Standings, pp *Participant) []string {
+	res := []string{pp.Place.String()}
+	if !conf.HideLogins {
+		res = append(res, pp.Login)
+	}
+	if conf.DisplayNames {
+		res = append(res, pp.Name)
+	}
+	if conf.DisplayTeams {
+		res = append(res, teamName(conf, pp.TeamID))
+	}
+	if st.IsICPC() {
+		for _, t := range pp.Tasks {
+			res = append(res, formatExportCell(t))
+		}
+		return append(res, strconv.Itoa(pp.Solved), strconv.Itoa(pp.Penalty))
+	}
+	for _, t := range pp.Tasks {
+		res = append(res, formatExportScore(t.Score))
+	}
+	if st.IsNormalized() {
+		return append(res, formatExportScore(pp.Total), formatExportScore(pp.RawTotal))
+	}
+	return append(res, formatExportScore(pp.Total))
+}
+
+func teamName(conf *Config, teamID int) string {
+	if teamID < 0 || teamID >= len(conf.Teams) {
+		return ""
+	}
+	return conf.Teams[teamID].Name
+}
+
+func buildExport(conf *Config, st *Standings, format ExportFormat, bom bool) ([]byte, error) {
+	var b bytes.Buffer
+	if bom {
+		_, _ = b.WriteString(utf8BOM)
+	}
+	wr := csv.NewWriter(&b)
+	wr.Comma = format.Comma
+	if err := wr.Write(exportHeader(conf, st)); err != nil {
+		return nil, fmt.Errorf("writing header: %w", err)
+	}
+	for i := range st.Participants {
+		if err := wr.Write(exportParticipant(conf, st, &st.Participants[i])); err != nil {
+			return nil, fmt.Errorf("writing participant: %w", err)
+		}
+	}
+	wr.Flush()
+	if err := wr.Error(); err != nil {
+		return nil, fmt.Errorf("flushing: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (p *Presenter) getSnapshotForExport(w http.ResponseWriter, req *http.Request) (*Snapshot, bool) {
+	snap, err := p.getSnapshot(isJury(req))
+	if errors.Is(err, ErrStandingsNotLoaded) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "standings are not loaded yet, please try again later")
+		return nil, false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		p.logger.Error("error serving export", zap.Error(err))
+		_, _ = io.WriteString(w, "got error: "+err.Error())
+		return nil, false
+	}
+	return snap, true
+}
+
+func (p *Presenter) ExportHandler(format ExportFormat) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p.logger.Info("export", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_, _ = io.WriteString(w, "use GET method")
+			return
+		}
+		snap, ok := p.getSnapshotForExport(w, req)
+		if !ok {
+			return
+		}
+		bom, _ := strconv.ParseBool(req.URL.Query().Get("bom"))
+		conf := p.renderConf(snap)
+		b, err := buildExport(conf, parseFilter(req, conf).apply(snap.Standings), format, bom)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			p.logger.Error("error building export", zap.Error(err))
+			_, _ = io.WriteString(w, "got error: "+err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"standings.%v\"", format.Ext))
+		_, _ = w.Write(b)
+	})
+}
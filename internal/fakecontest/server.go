@@ -0,0 +1,226 @@
+package fakecontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Title struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+type ParticipantInfo struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+type ProblemResult struct {
+	Score           string `json:"score"`
+	Status          string `json:"status,omitempty"`
+	SubmissionCount string `json:"submissionCount,omitempty"`
+	SubmitDelay     int    `json:"submitDelay,omitempty"`
+}
+
+type Row struct {
+	ParticipantInfo ParticipantInfo `json:"participantInfo"`
+	ProblemResults  []ProblemResult `json:"problemResults"`
+}
+
+type Standings struct {
+	Titles []Title `json:"titles"`
+	Rows   []Row   `json:"rows"`
+}
+
+type Response struct {
+	Status int
+	Header http.Header
+	Body   string
+}
+
+func RateLimited(retryAfter int) Response {
+	return Response{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": []string{strconv.Itoa(retryAfter)}},
+		Body:   `{"message": "too many requests"}`,
+	}
+}
+
+func ServerError(status int) Response {
+	return Response{
+		Status: status,
+		Body:   `{"message": "internal error"}`,
+	}
+}
+
+func Unauthorized() Response {
+	return Response{
+		Status: http.StatusUnauthorized,
+		Body:   `{"message": "unauthorized"}`,
+	}
+}
+
+func Malformed() Response {
+	return Response{
+		Status: http.StatusOK,
+		Body:   `{"titles": [{"name": "A", "title": "A"}], "rows": [{"participantInfo":`,
+	}
+}
+
+type contest struct {
+	st      Standings
+	pending []Response
+}
+
+type Server struct {
+	mu       sync.Mutex
+	contests map[int]*contest
+	requests int
+}
+
+var standingsPath = regexp.MustCompile(`^/api/public/v2/contests/([0-9]+)/standings$`)
+
+func NewServer() *Server {
+	return &Server{
+		contests: make(map[int]*contest),
+	}
+}
+
+func (s *Server) getContestUnlocked(id int) *contest {
+	c, ok := s.contests[id]
+	if !ok {
+		c = &contest{}
+		s.contests[id] = c
+	}
+	return c
+}
+
+func (s *Server) SetStandings(id int, st Standings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.getContestUnlocked(id).st = st
+}
+
+func (s *Server) EnqueueResponses(id int, rsps ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.getContestUnlocked(id)
+	c.pending = append(c.pending, rsps...)
+}
+
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) LoadFixtures(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading fixtures dir: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("reading fixture %q: %w", name, err)
+		}
+		var st Standings
+		if err := json.Unmarshal(data, &st); err != nil {
+			return fmt.Errorf("decoding fixture %q: %w", name, err)
+		}
+		s.SetStandings(id, st)
+	}
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, rsp Response) {
+	for k, v := range rsp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rsp.Status)
+	_, _ = io.WriteString(w, rsp.Body)
+}
+
+func intParam(req *http.Request, name string, def int) (int, error) {
+	val := req.URL.Query().Get(name)
+	if val == "" {
+		return def, nil
+	}
+	res, err := strconv.Atoi(val)
+	if err != nil || res <= 0 {
+		return 0, fmt.Errorf("bad %v", name)
+	}
+	return res, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m := standingsPath.FindStringSubmatch(req.URL.Path)
+	if req.Method != http.MethodGet || m == nil {
+		writeResponse(w, Response{Status: http.StatusNotFound, Body: `{"message": "not found"}`})
+		return
+	}
+	id, _ := strconv.Atoi(m[1])
+	page, err := intParam(req, "page", 1)
+	if err != nil {
+		writeResponse(w, Response{Status: http.StatusBadRequest, Body: fmt.Sprintf(`{"message": %q}`, err.Error())})
+		return
+	}
+	pageSize, err := intParam(req, "pageSize", 100)
+	if err != nil {
+		writeResponse(w, Response{Status: http.StatusBadRequest, Body: fmt.Sprintf(`{"message": %q}`, err.Error())})
+		return
+	}
+
+	s.mu.Lock()
+	s.requests++
+	c, ok := s.contests[id]
+	if !ok {
+		s.mu.Unlock()
+		writeResponse(w, Response{Status: http.StatusNotFound, Body: `{"message": "contest not found"}`})
+		return
+	}
+	if len(c.pending) != 0 {
+		rsp := c.pending[0]
+		c.pending = c.pending[1:]
+		s.mu.Unlock()
+		writeResponse(w, rsp)
+		return
+	}
+	res := Standings{
+		Titles: c.st.Titles,
+		Rows:   []Row{},
+	}
+	lo := min((page-1)*pageSize, len(c.st.Rows))
+	hi := min(lo+pageSize, len(c.st.Rows))
+	res.Rows = append(res.Rows, c.st.Rows[lo:hi]...)
+	s.mu.Unlock()
+
+	data, err := json.Marshal(&res)
+	if err != nil {
+		writeResponse(w, ServerError(http.StatusInternalServerError))
+		return
+	}
+	writeResponse(w, Response{Status: http.StatusOK, Body: string(data)})
+}
+
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
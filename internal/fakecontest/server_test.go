@@ -0,0 +1,71 @@
+package fakecontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func get(t *testing.T, url string) (*http.Response, *Standings) {
+	t.Helper()
+	rsp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("requesting %v: %v", url, err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return rsp, nil
+	}
+	var st Standings
+	if err := json.NewDecoder(rsp.Body).Decode(&st); err != nil {
+		t.Fatalf("decoding %v: %v", url, err)
+	}
+	return rsp, &st
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	if err := s.LoadFixtures("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	srv := s.Start()
+	defer srv.Close()
+	page := func(id, page, pageSize int) string {
+		return fmt.Sprintf("%v/api/public/v2/contests/%v/standings?page=%v&pageSize=%v", srv.URL, id, page, pageSize)
+	}
+
+	_, st := get(t, page(1, 2, 3))
+	if st == nil || len(st.Rows) != 1 || len(st.Titles) != 3 {
+		t.Fatalf("got page %+v, want 1 row and 3 titles", st)
+	}
+	_, st = get(t, page(1, 3, 3))
+	if st == nil || len(st.Rows) != 0 {
+		t.Errorf("got page %+v past the end, want no rows", st)
+	}
+
+	s.EnqueueResponses(1, RateLimited(5), Malformed())
+	rsp, _ := get(t, page(1, 1, 3))
+	if rsp.StatusCode != http.StatusTooManyRequests || rsp.Header.Get("Retry-After") != "5" {
+		t.Errorf("got status %v and Retry-After %q, want 429 and 5", rsp.StatusCode, rsp.Header.Get("Retry-After"))
+	}
+	rsp, err := http.Get(page(1, 1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dummy Standings
+	if err := json.NewDecoder(rsp.Body).Decode(&dummy); err == nil {
+		t.Error("malformed response is decoded")
+	}
+	rsp.Body.Close()
+	if _, st := get(t, page(1, 1, 3)); st == nil || len(st.Rows) != 3 {
+		t.Errorf("got page %+v after the queued responses, want 3 rows", st)
+	}
+
+	if rsp, _ := get(t, page(42, 1, 3)); rsp.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %v for unknown contest, want 404", rsp.StatusCode)
+	}
+	if n := s.Requests(); n != 6 {
+		t.Errorf("got %v requests, want 6", n)
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type fileSource struct {
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	st      *Standings
+}
+
+func parseStandingsJSON(r io.Reader) (*Standings, error) {
+	d := json.NewDecoder(r)
+	var st Standings
+	err := d.Decode(&st)
+	if err != nil {
+		return nil, fmt.Errorf("decoding json standings: %w", err)
+	}
+	return &st, nil
+}
+
+func parseStandingsCSV(r io.Reader) (*Standings, error) {
+	rd := csv.NewReader(r)
+	rd.TrimLeadingSpace = true
+	records, err := rd.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header in csv")
+	}
+
+	loginCol := -1
+	nameCol := -1
+	var taskCols []int
+	var st Standings
+	for i, col := range records[0] {
+		if i == 0 {
+			col = strings.TrimPrefix(col, utf8BOM)
+		}
+		switch strings.ToLower(strings.TrimSpace(col)) {
+		case "login":
+			loginCol = i
+		case "name":
+			nameCol = i
+		default:
+			taskCols = append(taskCols, i)
+			st.Header.Tasks = append(st.Header.Tasks, TaskHeader{
+				Name:  col,
+				Title: col,
+			})
+		}
+	}
+	if loginCol == -1 {
+		return nil, fmt.Errorf("no login column in csv")
+	}
+
+	for i, rec := range records[1:] {
+		p := Participant{
+			Login: rec[loginCol],
+		}
+		if nameCol != -1 {
+			p.Name = rec[nameCol]
+		}
+		for _, col := range taskCols {
+			score, err := parseScore(strings.TrimSpace(rec[col]))
+			if err != nil {
+				return nil, fmt.Errorf("row %v: decoding float score %q: %w", i+2, rec[col], err)
+			}
+			p.Tasks = append(p.Tasks, ParticipantCell{
+				Score: score,
+			})
+		}
+		st.Participants = append(st.Participants, p)
+	}
+	return &st, nil
+}
+
+func readStandingsFile(path string) (*Standings, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer f.Close()
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return parseStandingsJSON(f)
+	case ".csv":
+		return parseStandingsCSV(f)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q", ext)
+	}
+}
+
+func (s *fileSource) FetchStandings(_ context.Context, contest Contest) (*Standings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(contest.Path)
+	if err != nil {
+		return nil, fmt.Errorf("getting file info: %w", err)
+	}
+	if s.st == nil || !info.ModTime().Equal(s.modTime) || info.Size() != s.size {
+		st, err := readStandingsFile(contest.Path)
+		if err != nil {
+			return nil, err
+		}
+		st.Tag = contest.Tag
+		err = st.ValidateAndFix()
+		if err != nil {
+			return nil, fmt.Errorf("validating standings: %w", err)
+		}
+		s.st = st
+		s.modTime = info.ModTime()
+		s.size = info.Size()
+	}
+
+	// The caller modifies the task headers and the participants, so do not share them with the cache.
+	res := *s.st
+	res.Header.Tasks = slices.Clone(s.st.Header.Tasks)
+	res.Participants = slices.Clone(s.st.Participants)
+	return &res, nil
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStandingsCSV(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		wantTasks  []string
+		wantLogins []string
+		wantNames  []string
+		wantScores [][]float64
+		wantErr    string
+	}{
+		{
+			name:       "basic",
+			src:        "login,A,B\nalice,100,50\nbob,,20\n",
+			wantTasks:  []string{"A", "B"},
+			wantLogins: []string{"alice", "bob"},
+			wantNames:  []string{"", ""},
+			wantScores: [][]float64{{100, 50}, {0, 20}},
+		},
+		{
+			name:       "bom, name column and spaces",
+			src:        utf8BOM + "Name, Login, A\nAlice, alice, \"50,5\"\nBob, bob, 7.25\n",
+			wantTasks:  []string{"A"},
+			wantLogins: []string{"alice", "bob"},
+			wantNames:  []string{"Alice", "Bob"},
+			wantScores: [][]float64{{50.5}, {7.25}},
+		},
+		{
+			name:       "no participants",
+			src:        "login,A\n",
+			wantTasks:  []string{"A"},
+			wantLogins: []string{},
+			wantNames:  []string{},
+			wantScores: [][]float64{},
+		},
+		{name: "empty", src: "", wantErr: "no header in csv"},
+		{name: "no login column", src: "name,A\nAlice,1\n", wantErr: "no login column in csv"},
+		{name: "bad score", src: "login,A\nalice,1\nbob,ok\n", wantErr: `row 3: decoding float score "ok"`},
+		{name: "wrong number of fields", src: "login,A\nalice,1,2\n", wantErr: "reading csv"},
+	}
+	for _, tt := range tests {
+		st, err := parseStandingsCSV(strings.NewReader(tt.src))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%v: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.name, err)
+			continue
+		}
+		tasks := []string{}
+		for _, h := range st.Header.Tasks {
+			tasks = append(tasks, h.Title)
+		}
+		logins, names, scores := []string{}, []string{}, [][]float64{}
+		for _, p := range st.Participants {
+			logins = append(logins, p.Login)
+			names = append(names, p.Name)
+			var row []float64
+			for _, c := range p.Tasks {
+				row = append(row, c.Score)
+			}
+			scores = append(scores, row)
+		}
+		if !slices.Equal(tasks, tt.wantTasks) || !slices.Equal(logins, tt.wantLogins) || !slices.Equal(names, tt.wantNames) {
+			t.Errorf("%v: got tasks %q, logins %q, names %q, want %q, %q, %q", tt.name, tasks, logins, names, tt.wantTasks, tt.wantLogins, tt.wantNames)
+		}
+		if !slices.EqualFunc(scores, tt.wantScores, slices.Equal[[]float64]) {
+			t.Errorf("%v: got scores %v, want %v", tt.name, scores, tt.wantScores)
+		}
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "d1.csv")
+	write := func(data string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.Now().Add(-time.Hour)
+	write("login,A\nalice,10\nbob,20\n", mtime)
+
+	src := &fileSource{}
+	ct := Contest{Type: ContestTypeFile, Path: path, Tag: "D1"}
+	st, err := src.FetchStandings(context.Background(), ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := participantLogins(st); !slices.Equal(got, []string{"bob", "alice"}) || st.Tag != "D1" {
+		t.Errorf("got tag %q and participants %v, want D1 with bob and alice", st.Tag, got)
+	}
+
+	// The returned standings are modified by the keeper, which must not affect the cached ones.
+	maxScore := 100.0
+	st.Header.Tasks[0].MaxScore = &maxScore
+	st.Participants[0].Login = "mallory"
+	st, err = src.FetchStandings(context.Background(), ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Header.Tasks[0].MaxScore != nil || st.Participants[0].Login != "bob" {
+		t.Error("cached standings are modified through the returned copy")
+	}
+
+	write("login,A\nalice,30\nbob,20\n", mtime.Add(time.Minute))
+	st, err = src.FetchStandings(context.Background(), ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := participantLogins(st); !slices.Equal(got, []string{"alice", "bob"}) {
+		t.Errorf("got participants %v after the file changed, want alice and bob", got)
+	}
+
+	write("login,A\nalice,oops\n", mtime.Add(2*time.Minute))
+	if _, err := src.FetchStandings(context.Background(), ct); err == nil {
+		t.Error("broken file is accepted")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.FetchStandings(context.Background(), ct); err == nil {
+		t.Error("missing file is accepted")
+	}
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const frozenStateDir = "secrets/frozen"
+
+var ErrFrozenStandingsMissing = errors.New("standings as of the freeze time are not available")
+
+// frozenState is the last standings of a contest fetched before its freeze time. It is stored on disk,
+// so the public standings stay frozen after restart.
+type frozenState struct {
+	Contest    Contest    `json:"contest"`
+	UpdateTime time.Time  `json:"update_time"`
+	Standings  *Standings `json:"standings"`
+}
+
+func frozenStatePath(ct Contest) string {
+	h := sha256.Sum256([]byte(ct.sourceKey() + "|" + ct.FreezeTime.UTC().Format(time.RFC3339Nano)))
+	return filepath.Join(frozenStateDir, hex.EncodeToString(h[:8])+".json")
+}
+
+func storeFrozenState(c *ContestStatus) error {
+	return writeStateFile(frozenStatePath(c.Contest), &frozenState{
+		Contest:    c.Contest,
+		UpdateTime: c.UpdateTime,
+		Standings:  c.Standings,
+	})
+}
+
+// writeStateFile atomically replaces the file in frozenStateDir with the JSON-encoded value.
+func writeStateFile(name string, v any) error {
+	if err := os.MkdirAll(frozenStateDir, 0o700); err != nil {
+		return fmt.Errorf("creating dir: %w", err)
+	}
+	f, err := os.CreateTemp(frozenStateDir, "state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	e := json.NewEncoder(f)
+	if err := e.Encode(v); err != nil {
+		return fmt.Errorf("encoding json: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("renaming file: %w", err)
+	}
+	return nil
+}
+
+// loadFrozenState returns the stored frozen standings of the contest, or nil if there are none.
+func loadFrozenState(ct Contest) (*ContestStatus, error) {
+	data, err := os.ReadFile(frozenStatePath(ct))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	var s frozenState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding json: %w", err)
+	}
+	if s.Standings == nil || s.Contest.sourceKey() != ct.sourceKey() || !s.UpdateTime.Before(*ct.FreezeTime) {
+		return nil, nil
+	}
+	return &ContestStatus{
+		Contest:    ct,
+		Standings:  s.Standings,
+		UpdateTime: s.UpdateTime,
+	}, nil
+}
+
+// unfreezeRecord states that the jury unfroze the contest with the given source key frozen at FreezeTime.
+// The records are stored on disk, so the revealed results are not hidden again after restart.
+type unfreezeRecord struct {
+	Source     string    `json:"source"`
+	FreezeTime time.Time `json:"freeze_time"`
+}
+
+func unfrozenPath() string {
+	return filepath.Join(frozenStateDir, "unfrozen.json")
+}
+
+func storeUnfrozen(unfrozen map[string]time.Time) error {
+	records := make([]unfreezeRecord, 0, len(unfrozen))
+	for source, t := range unfrozen {
+		records = append(records, unfreezeRecord{Source: source, FreezeTime: t})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Source < records[j].Source
+	})
+	return writeStateFile(unfrozenPath(), records)
+}
+
+// loadUnfrozen returns the stored freeze times of the unfrozen contests, keyed by Contest.sourceKey().
+func loadUnfrozen() (map[string]time.Time, error) {
+	res := make(map[string]time.Time)
+	data, err := os.ReadFile(unfrozenPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return res, nil
+		}
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	var records []unfreezeRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("decoding json: %w", err)
+	}
+	for _, r := range records {
+		res[r.Source] = r.FreezeTime
+	}
+	return res, nil
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+type IdentityResolver struct {
+	aliases     map[string]string
+	mergeByName bool
+}
+
+func NewIdentityResolver(conf *Config) *IdentityResolver {
+	return &IdentityResolver{
+		aliases:     conf.Aliases,
+		mergeByName: conf.MergeByName,
+	}
+}
+
+func (r *IdentityResolver) canonicalLogins(sts []*Standings) map[string]string {
+	res := make(map[string]string)
+	byName := make(map[string]string)
+	for _, st := range sts {
+		if st == nil {
+			continue
+		}
+		for _, p := range st.Participants {
+			if _, ok := res[p.Login]; ok {
+				continue
+			}
+			login := p.Login
+			if alias, ok := r.aliases[login]; ok {
+				login = alias
+			}
+			if r.mergeByName && p.Name != "" {
+				if other, ok := byName[p.Name]; ok {
+					login = other
+				} else {
+					byName[p.Name] = login
+				}
+			}
+			res[p.Login] = login
+		}
+	}
+	return res
+}
+
+func (r *IdentityResolver) Resolve(logger *zap.Logger, sts []*Standings) ([]*Standings, []string, error) {
+	if len(r.aliases) == 0 && !r.mergeByName {
+		return sts, nil, nil
+	}
+	logins := r.canonicalLogins(sts)
+	var conflicts []string
+	res := make([]*Standings, len(sts))
+	for i, st := range sts {
+		if st == nil {
+			continue
+		}
+		seen := make(map[string]string)
+		rs := *st
+		rs.Participants = make([]Participant, 0, len(st.Participants))
+		for _, p := range st.Participants {
+			login := logins[p.Login]
+			if other, ok := seen[login]; ok {
+				msg := fmt.Sprintf("%v: logins %q and %q both resolve to %q, keeping the result of %q", st.Tag, other, p.Login, login, other)
+				logger.Warn("identity conflict", zap.String("tag", st.Tag), zap.String("login", login), zap.String("login1", other), zap.String("login2", p.Login))
+				conflicts = append(conflicts, msg)
+				continue
+			}
+			seen[login] = p.Login
+			p.Login = login
+			rs.Participants = append(rs.Participants, p)
+		}
+		if err := rs.ValidateAndFix(); err != nil {
+			return nil, nil, fmt.Errorf("validating standings: %w", err)
+		}
+		res[i] = &rs
+	}
+	return res, conflicts, nil
+}
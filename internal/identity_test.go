@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestIdentityResolve(t *testing.T) {
+	type entry struct {
+		login, name string
+		score       float64
+	}
+	standings := func(tag string, entries ...entry) *Standings {
+		st := &Standings{Tag: tag, Header: Header{Tasks: []TaskHeader{{Name: "A", Title: tag + "-A"}}}}
+		for _, e := range entries {
+			st.Participants = append(st.Participants, Participant{
+				Login: e.login,
+				Name:  e.name,
+				Tasks: []ParticipantCell{{Score: e.score}},
+			})
+		}
+		if err := st.ValidateAndFix(); err != nil {
+			panic(err)
+		}
+		return st
+	}
+	tests := []struct {
+		name          string
+		conf          Config
+		input         []*Standings
+		want          [][]string
+		wantConflicts []string
+	}{
+		{
+			name: "no resolution",
+			input: []*Standings{
+				standings("D1", entry{"a", "Alice", 10}, entry{"b", "Alice", 5}),
+			},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "aliases",
+			conf: Config{Aliases: map[string]string{"a2": "a"}},
+			input: []*Standings{
+				standings("D1", entry{"a", "", 10}, entry{"b", "", 5}),
+				standings("D2", entry{"a2", "", 10}),
+			},
+			want: [][]string{{"a", "b"}, {"a"}},
+		},
+		{
+			name: "alias conflict keeps first",
+			conf: Config{Aliases: map[string]string{"a2": "a"}},
+			input: []*Standings{
+				standings("D1", entry{"a", "", 10}, entry{"a2", "", 20}),
+			},
+			want:          [][]string{{"a"}},
+			wantConflicts: []string{`D1: logins "a2" and "a" both resolve to "a", keeping the result of "a2"`},
+		},
+		{
+			name: "merge by name",
+			conf: Config{MergeByName: true},
+			input: []*Standings{
+				standings("D1", entry{"a", "Alice", 10}, entry{"b", "Bob", 5}),
+				standings("D2", entry{"a-new", "Alice", 10}, entry{"c", "", 1}),
+			},
+			want: [][]string{{"a", "b"}, {"a", "c"}},
+		},
+		{
+			name: "merge by name conflict within contest",
+			conf: Config{MergeByName: true},
+			input: []*Standings{
+				standings("D1", entry{"a", "Alice", 10}, entry{"a-new", "Alice", 5}),
+			},
+			want:          [][]string{{"a"}},
+			wantConflicts: []string{`D1: logins "a" and "a-new" both resolve to "a", keeping the result of "a"`},
+		},
+		{
+			name: "empty names are not merged",
+			conf: Config{MergeByName: true},
+			input: []*Standings{
+				standings("D1", entry{"a", "", 10}, entry{"b", "", 5}),
+			},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "alias applies before merge by name",
+			conf: Config{Aliases: map[string]string{"x": "b"}, MergeByName: true},
+			input: []*Standings{
+				standings("D1", entry{"x", "Bob", 10}),
+				standings("D2", entry{"y", "Bob", 10}),
+			},
+			want: [][]string{{"b"}, {"b"}},
+		},
+		{
+			name: "missing standings are kept",
+			conf: Config{Aliases: map[string]string{"a2": "a"}},
+			input: []*Standings{
+				nil,
+				standings("D2", entry{"a2", "", 10}),
+			},
+			want: [][]string{nil, {"a"}},
+		},
+	}
+	for _, tt := range tests {
+		res, conflicts, err := NewIdentityResolver(&tt.conf).Resolve(zap.NewNop(), tt.input)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.name, err)
+			continue
+		}
+		var got [][]string
+		for _, st := range res {
+			if st == nil {
+				got = append(got, nil)
+				continue
+			}
+			got = append(got, participantLogins(st))
+		}
+		if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+			t.Errorf("%v: got logins %v, want %v", tt.name, got, tt.want)
+		}
+		if !slices.Equal(conflicts, tt.wantConflicts) {
+			t.Errorf("%v: got conflicts %q, want %q", tt.name, conflicts, tt.wantConflicts)
+		}
+	}
+}
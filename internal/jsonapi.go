@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type jsonContestStatus struct {
+	Type       string     `json:"type"`
+	ID         int        `json:"id"`
+	Tag        string     `json:"tag"`
+	Loaded     bool       `json:"loaded"`
+	Stale      bool       `json:"stale"`
+	UpdateTime *time.Time `json:"update_time,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorTime  *time.Time `json:"error_time,omitempty"`
+}
+
+type jsonParticipant struct {
+	Rank     int               `json:"rank"`
+	Place    Place             `json:"place"`
+	Login    string            `json:"login,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	TeamID   *int              `json:"team_id,omitempty"`
+	Team     string            `json:"team,omitempty"`
+	Tasks    []ParticipantCell `json:"tasks"`
+	Total    float64           `json:"total"`
+	RawTotal float64           `json:"raw_total"`
+	Solved   int               `json:"solved"`
+	Penalty  int               `json:"penalty"`
+	Days     []DayResult       `json:"days,omitempty"`
+}
+
+type jsonStandings struct {
+	FetchTime    time.Time           `json:"fetch_time"`
+	Stale        bool                `json:"stale"`
+	Frozen       bool                `json:"frozen,omitempty"`
+	FreezeTime   *time.Time          `json:"freeze_time,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	Contests     []jsonContestStatus `json:"contests"`
+	Conflicts    []string            `json:"conflicts,omitempty"`
+	Ranking      RankingMode         `json:"ranking"`
+	Aggregation  *Aggregation        `json:"aggregation,omitempty"`
+	Header       Header              `json:"header"`
+	Participants []jsonParticipant   `json:"participants"`
+}
+
+type jsonTeamMember struct {
+	jsonParticipant
+	Counted bool `json:"counted"`
+}
+
+type jsonTeam struct {
+	Rank       int              `json:"rank"`
+	Place      Place            `json:"place"`
+	TeamID     int              `json:"team_id"`
+	Name       string           `json:"name"`
+	NumMembers int              `json:"num_members"`
+	NumCounted int              `json:"num_counted"`
+	Total      float64          `json:"total"`
+	Solved     float64          `json:"solved"`
+	Penalty    float64          `json:"penalty"`
+	Members    []jsonTeamMember `json:"members"`
+}
+
+type jsonTeamStandings struct {
+	FetchTime time.Time   `json:"fetch_time"`
+	Stale     bool        `json:"stale"`
+	Frozen    bool        `json:"frozen,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Ranking   RankingMode `json:"ranking"`
+	Team      TeamRanking `json:"team_ranking"`
+	Header    Header      `json:"header"`
+	Teams     []jsonTeam  `json:"teams"`
+}
+
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+func optTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func buildJSON(conf *Config, snap *Snapshot, f filter, jury bool) *jsonStandings {
+	st := f.apply(snap.Standings)
+	res := &jsonStandings{
+		FetchTime:    snap.UpdateTime,
+		Stale:        snap.Stale(),
+		Frozen:       snap.Frozen,
+		FreezeTime:   optTime(snap.FreezeTime),
+		Error:        errString(snap.Err),
+		Contests:     make([]jsonContestStatus, len(snap.Contests)),
+		Ranking:      st.Ranking,
+		Aggregation:  st.Aggregation,
+		Header:       st.Header,
+		Participants: make([]jsonParticipant, len(st.Participants)),
+	}
+	if jury {
+		// The conflicts contain the raw logins, so they are not public.
+		res.Conflicts = snap.Conflicts
+	}
+	for i, c := range snap.Contests {
+		res.Contests[i] = jsonContestStatus{
+			Type:       c.Contest.Type,
+			ID:         c.Contest.ID,
+			Tag:        c.Contest.Tag,
+			Loaded:     c.Standings != nil,
+			Stale:      c.Stale(),
+			UpdateTime: optTime(c.UpdateTime),
+			Error:      errString(c.Err),
+			ErrorTime:  optTime(c.ErrTime),
+		}
+	}
+	for i, pp := range st.Participants {
+		res.Participants[i] = buildJSONParticipant(conf, pp)
+	}
+	return res
+}
+
+func buildJSONParticipant(conf *Config, pp Participant) jsonParticipant {
+	jp := jsonParticipant{
+		Rank:     pp.Place.Lo,
+		Place:    pp.Place,
+		Tasks:    pp.Tasks,
+		Total:    pp.Total,
+		RawTotal: pp.RawTotal,
+		Solved:   pp.Solved,
+		Penalty:  pp.Penalty,
+		Days:     pp.Days,
+	}
+	if !conf.HideLogins {
+		jp.Login = pp.Login
+	}
+	if conf.DisplayNames {
+		jp.Name = pp.Name
+	}
+	if conf.DisplayTeams && pp.TeamID >= 0 && pp.TeamID < len(conf.Teams) {
+		teamID := pp.TeamID
+		jp.TeamID = &teamID
+		jp.Team = teamName(conf, teamID)
+	}
+	return jp
+}
+
+func buildTeamsJSON(conf *Config, snap *Snapshot, ts *TeamStandings) *jsonTeamStandings {
+	res := &jsonTeamStandings{
+		FetchTime: snap.UpdateTime,
+		Stale:     snap.Stale(),
+		Frozen:    snap.Frozen,
+		Error:     errString(snap.Err),
+		Ranking:   snap.Standings.Ranking,
+		Team:      ts.Ranking,
+		Header:    snap.Standings.Header,
+		Teams:     make([]jsonTeam, len(ts.Teams)),
+	}
+	for i, t := range ts.Teams {
+		jt := jsonTeam{
+			Rank:       t.Place.Lo,
+			Place:      t.Place,
+			TeamID:     t.TeamID,
+			Name:       t.Name,
+			NumMembers: t.NumMembers(),
+			NumCounted: t.NumCounted(),
+			Total:      t.Total,
+			Solved:     t.Solved,
+			Penalty:    t.Penalty,
+			Members:    make([]jsonTeamMember, len(t.Members)),
+		}
+		for j, pp := range t.Members {
+			jt.Members[j] = jsonTeamMember{
+				jsonParticipant: buildJSONParticipant(conf, pp),
+				Counted:         t.Counted[j],
+			}
+		}
+		res.Teams[i] = jt
+	}
+	return res
+}
+
+func (p *Presenter) writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	e := json.NewEncoder(w)
+	if err := e.Encode(value); err != nil {
+		p.logger.Warn("error writing json response", zap.Error(err))
+	}
+}
+
+func (p *Presenter) ServeJSON(w http.ResponseWriter, req *http.Request) {
+	p.logger.Info("get json", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if req.Method != http.MethodGet {
+		p.writeJSON(w, http.StatusMethodNotAllowed, &jsonError{Error: "use GET method"})
+		return
+	}
+	snap, err := p.getSnapshot(isJury(req))
+	if errors.Is(err, ErrStandingsNotLoaded) {
+		p.writeJSON(w, http.StatusServiceUnavailable, &jsonError{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		p.logger.Error("error serving json request", zap.Error(err))
+		p.writeJSON(w, http.StatusInternalServerError, &jsonError{Error: err.Error()})
+		return
+	}
+	conf := p.renderConf(snap)
+	p.writeJSON(w, http.StatusOK, buildJSON(conf, snap, parseFilter(req, conf), isJury(req)))
+}
+
+func (p *Presenter) ServeTeamsJSON(w http.ResponseWriter, req *http.Request) {
+	p.logger.Info("get teams json", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if req.Method != http.MethodGet {
+		p.writeJSON(w, http.StatusMethodNotAllowed, &jsonError{Error: "use GET method"})
+		return
+	}
+	if !p.conf().DisplayTeams {
+		p.writeJSON(w, http.StatusNotFound, &jsonError{Error: "team standings are disabled"})
+		return
+	}
+	snap, err := p.getSnapshot(isJury(req))
+	if errors.Is(err, ErrStandingsNotLoaded) {
+		p.writeJSON(w, http.StatusServiceUnavailable, &jsonError{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		p.logger.Error("error serving json request", zap.Error(err))
+		p.writeJSON(w, http.StatusInternalServerError, &jsonError{Error: err.Error()})
+		return
+	}
+	conf := p.renderConf(snap)
+	ts, err := BuildTeamStandings(snap.Standings, conf.Teams, parseTeamFilter(req, conf).ranking)
+	if err != nil {
+		p.writeJSON(w, http.StatusBadRequest, &jsonError{Error: err.Error()})
+		return
+	}
+	p.writeJSON(w, http.StatusOK, buildTeamsJSON(conf, snap, ts))
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+type juryKey struct{}
+
+type adminKey struct{}
+
+func isJury(req *http.Request) bool {
+	v, _ := req.Context().Value(juryKey{}).(bool)
+	return v
+}
+
+func isAdmin(req *http.Request) bool {
+	v, _ := req.Context().Value(adminKey{}).(bool)
+	return v
+}
+
+func basicAuthHandler(logger *zap.Logger, realm string, password string, key any, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if password == "" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, realm+" views are disabled")
+			return
+		}
+		_, pass, ok := req.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
+			if ok {
+				logger.Warn("wrong password", zap.String("realm", realm), zap.String("addr", req.RemoteAddr))
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm))
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, "unauthorized")
+			return
+		}
+		if req.Method != http.MethodGet && req.Method != http.MethodHead && !sameOrigin(req) {
+			logger.Warn("cross-origin request rejected", zap.String("realm", realm), zap.String("addr", req.RemoteAddr), zap.String("origin", req.Header.Get("Origin")))
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, "cross-origin request")
+			return
+		}
+		h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), key, true)))
+	})
+}
+
+// Browsers send basic auth credentials with cross-site form submissions, so state-changing requests
+// must come from the same origin.
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == req.Host
+}
+
+func JuryHandler(logger *zap.Logger, password string, h http.Handler) http.Handler {
+	return basicAuthHandler(logger, "jury", password, juryKey{}, h)
+}
+
+func AdminHandler(logger *zap.Logger, password string, h http.Handler) http.Handler {
+	return basicAuthHandler(logger, "admin", password, adminKey{}, h)
+}
+
+func (p *Presenter) getSnapshot(jury bool) (*Snapshot, error) {
+	if jury {
+		return p.k.GetJury()
+	}
+	return p.k.Get()
+}
+
+func (p *Presenter) ServeUnfreeze(w http.ResponseWriter, req *http.Request) {
+	p.logger.Info("unfreeze", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if !isJury(req) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "forbidden")
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "use POST method")
+		return
+	}
+	p.k.Unfreeze()
+	redirectToIndex(w)
+}
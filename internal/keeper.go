@@ -2,7 +2,9 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"sync"
 	"time"
 
@@ -10,116 +12,532 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+type ContestStatus struct {
+	Contest    Contest
+	Standings  *Standings
+	UpdateTime time.Time
+	Err        error
+	ErrTime    time.Time
+	Latency    time.Duration
+
+	failures  int
+	nextFetch time.Time
+}
+
+func (s *ContestStatus) Stale() bool {
+	return s.Err != nil
+}
+
+type Snapshot struct {
+	Standings  *Standings
+	Conf       *Config // config the standings were built with, the team IDs refer to its teams
+	Contests   []ContestStatus
+	Conflicts  []string
+	UpdateTime time.Time
+	Err        error
+	ErrTime    time.Time
+	Frozen     bool
+	FreezeTime time.Time
+}
+
+func (s *Snapshot) Stale() bool {
+	if s.Standings == nil {
+		return false
+	}
+	if s.Err != nil {
+		return true
+	}
+	for i := range s.Contests {
+		if s.Contests[i].Stale() {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrStandingsNotLoaded = errors.New("standings are not loaded yet")
+
 type Keeper struct {
-	conf  *Config
-	api   *Api
-	teams *TeamAssigner
+	store     *ConfigStore
+	api       *Api
+	seenConf  *Config
+	conf      *Config
+	agg       *Aggregation
+	identity  *IdentityResolver
+	sources   []StandingsSource
+	teams     *TeamAssigner
+	whitelist *regexp.Regexp
+	blacklist *regexp.Regexp
+	logger    *zap.Logger
+	refreshCh chan struct{}
+	contests  []ContestStatus
+	frozen    []ContestStatus
 
-	mu        sync.RWMutex
-	st        *Standings
-	err       error
-	fetched   bool
-	fetchTime time.Time
+	unfreezeMu sync.Mutex
+	unfrozen   map[string]time.Time // freeze times of the unfrozen contests, keyed by Contest.sourceKey()
+
+	mu          sync.RWMutex
+	snap        *Snapshot
+	jurySnap    *Snapshot
+	unfreezeGen int
 }
 
-func NewKeeper(conf *Config, api *Api) (*Keeper, error) {
-	teams, err := NewTeamAssigner(conf)
+func NewKeeper(logger *zap.Logger, store *ConfigStore, api *Api) (*Keeper, error) {
+	k := &Keeper{
+		store:     store,
+		api:       api,
+		logger:    logger,
+		refreshCh: make(chan struct{}, 1),
+		snap:      &Snapshot{},
+		jurySnap:  &Snapshot{},
+	}
+	unfrozen, err := loadUnfrozen()
 	if err != nil {
-		return nil, fmt.Errorf("creating team assigner: %w", err)
+		return nil, fmt.Errorf("loading unfrozen contests: %w", err)
+	}
+	k.unfrozen = unfrozen
+	k.seenConf = store.Get()
+	if err := k.reconfigure(k.seenConf); err != nil {
+		return nil, err
 	}
-	return &Keeper{
-		conf:  conf,
-		api:   api,
-		teams: teams,
-	}, nil
+	return k, nil
 }
 
-func (k *Keeper) Get(ctx context.Context, logger *zap.Logger) (*Standings, error) {
-	st, err, ok := k.tryGetSimple()
-	if ok {
-		return st, err
+func (k *Keeper) reconfigure(conf *Config) error {
+	teams, err := NewTeamAssigner(conf)
+	if err != nil {
+		return fmt.Errorf("creating team assigner: %w", err)
+	}
+	var whitelist, blacklist *regexp.Regexp
+	if conf.LoginWhitelistRegex != nil {
+		whitelist, err = regexp.Compile(*conf.LoginWhitelistRegex)
+		if err != nil {
+			return fmt.Errorf("compiling login whitelist regex: %w", err)
+		}
+	}
+	if conf.LoginBlacklistRegex != nil {
+		blacklist, err = regexp.Compile(*conf.LoginBlacklistRegex)
+		if err != nil {
+			return fmt.Errorf("compiling login blacklist regex: %w", err)
+		}
+	}
+	// Keep the fetched standings and sources of the contests that are still present in the new config.
+	used := make([]bool, len(k.contests))
+	contests := make([]ContestStatus, len(conf.Contests))
+	frozen := make([]ContestStatus, len(conf.Contests))
+	sources := make([]StandingsSource, len(conf.Contests))
+	for i, ct := range conf.Contests {
+		found := false
+		for j := range k.contests {
+			if !used[j] && k.contests[j].Contest.sourceKey() == ct.sourceKey() {
+				used[j] = true
+				contests[i] = k.contests[j]
+				frozen[i] = k.frozen[j]
+				sources[i] = k.sources[j]
+				found = true
+				break
+			}
+		}
+		if !found {
+			sources[i], err = NewStandingsSource(k.logger, k.store, ct, k.api)
+			if err != nil {
+				return fmt.Errorf("creating source for %v: %w", ct, err)
+			}
+		}
+		contests[i].Contest = ct
+		frozen[i].Contest = ct
+		frozen[i] = k.restoreFrozen(frozen[i])
+	}
+	k.conf = conf
+	k.agg = NewAggregation(conf)
+	k.identity = NewIdentityResolver(conf)
+	k.sources = sources
+	k.teams = teams
+	k.whitelist = whitelist
+	k.blacklist = blacklist
+	k.contests = contests
+	k.frozen = frozen
+
+	// Forget the unfreezes of the removed contests and of the contests whose freeze time has changed.
+	k.unfreezeMu.Lock()
+	unfrozen := make(map[string]time.Time)
+	for _, ct := range conf.Contests {
+		if t, ok := k.unfrozen[ct.sourceKey()]; ok && ct.FreezeTime != nil && t.Equal(*ct.FreezeTime) {
+			unfrozen[ct.sourceKey()] = t
+		}
 	}
-	return k.doGetHeavy(ctx, logger)
+	k.unfrozen = unfrozen
+	k.unfreezeMu.Unlock()
+	return nil
 }
 
-func (k *Keeper) needsFetchUnlocked() bool {
-	if !k.fetched {
-		return true
+// restoreFrozen drops the frozen standings fetched after the freeze time (if it was moved earlier) and
+// loads the stored ones if there are none in memory, for example after restart.
+func (k *Keeper) restoreFrozen(frozen ContestStatus) ContestStatus {
+	ct := frozen.Contest
+	if ct.FreezeTime == nil {
+		return ContestStatus{Contest: ct}
 	}
-	var interval time.Duration
-	if k.err == nil {
-		interval = k.conf.RefreshDuration
-	} else {
-		interval = k.conf.ErrorRefreshDuration
+	if frozen.Standings != nil && frozen.UpdateTime.Before(*ct.FreezeTime) {
+		return frozen
 	}
-	if time.Now().After(k.fetchTime.Add(interval)) {
-		return true
+	loaded, err := loadFrozenState(ct)
+	if err != nil {
+		k.logger.Error("cannot load frozen standings", zap.Stringer("contest", ct), zap.Error(err))
 	}
-	return false
+	if loaded == nil {
+		return ContestStatus{Contest: ct}
+	}
+	k.logger.Info("loaded frozen standings", zap.Stringer("contest", ct), zap.Time("update_time", loaded.UpdateTime))
+	return *loaded
 }
 
-func (k *Keeper) tryGetSimple() (*Standings, error, bool) {
+func checkSnapshot(snap *Snapshot) (*Snapshot, error) {
+	if snap.Standings == nil {
+		if snap.Err != nil {
+			return nil, snap.Err
+		}
+		return nil, ErrStandingsNotLoaded
+	}
+	return snap, nil
+}
+
+func (k *Keeper) Get() (*Snapshot, error) {
 	k.mu.RLock()
 	defer k.mu.RUnlock()
-	if !k.needsFetchUnlocked() {
-		return k.st, k.err, true
-	}
-	return nil, nil, false
+	return checkSnapshot(k.snap)
 }
 
-func (k *Keeper) doGetHeavy(ctx context.Context, logger *zap.Logger) (*Standings, error) {
+func (k *Keeper) GetJury() (*Snapshot, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return checkSnapshot(k.jurySnap)
+}
+
+func (k *Keeper) JurySnapshot() *Snapshot {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.jurySnap
+}
+
+// Unfreeze reveals the actual results of all the contests that are frozen now. The contests which are
+// frozen later still freeze at their freeze time.
+func (k *Keeper) Unfreeze() {
+	now := time.Now()
 	k.mu.Lock()
 	defer k.mu.Unlock()
+	var names []string
+	k.unfreezeMu.Lock()
+	for _, c := range k.jurySnap.Contests {
+		ct := c.Contest
+		if ct.FreezeTime != nil && !now.Before(*ct.FreezeTime) {
+			k.unfrozen[ct.sourceKey()] = *ct.FreezeTime
+			names = append(names, ct.String())
+		}
+	}
+	if len(names) != 0 {
+		if err := storeUnfrozen(k.unfrozen); err != nil {
+			k.logger.Error("cannot store unfrozen contests", zap.Error(err))
+		}
+	}
+	k.unfreezeMu.Unlock()
+	if len(names) == 0 {
+		return
+	}
+	k.logger.Info("unfreezing standings", zap.Strings("contests", names))
+	snap := *k.jurySnap
+	snap.Frozen = false
+	snap.FreezeTime = time.Time{}
+	k.jurySnap = &snap
+	k.snap = &snap
+	k.unfreezeGen++
+}
 
-	if !k.needsFetchUnlocked() {
-		return k.st, k.err
+func (k *Keeper) isFrozen(ct Contest, now time.Time) bool {
+	if ct.FreezeTime == nil || now.Before(*ct.FreezeTime) {
+		return false
 	}
+	k.unfreezeMu.Lock()
+	defer k.unfreezeMu.Unlock()
+	t, ok := k.unfrozen[ct.sourceKey()]
+	return !ok || !t.Equal(*ct.FreezeTime)
+}
 
-	res := make([]*Standings, len(k.conf.Contests))
+func (k *Keeper) Refresh() {
+	select {
+	case k.refreshCh <- struct{}{}:
+	default:
+	}
+}
 
-	logger.Info("refreshing standings", zap.Time("fetch_time", k.fetchTime))
-	g, _ := errgroup.WithContext(ctx)
-	for i := range k.conf.Contests {
-		i := i
-		ct := k.conf.Contests[i]
+func (k *Keeper) Run(ctx context.Context) {
+	force := true
+	for {
+		next := k.refresh(ctx, force)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-k.refreshCh:
+			timer.Stop()
+			force = true
+		case <-timer.C:
+			force = false
+		}
+	}
+}
+
+// errorBackoff returns the delay before refetching a contest after the given number of consecutive failures.
+// It starts from ErrorRefreshDuration and doubles with each failure, but never exceeds RefreshDuration.
+func (k *Keeper) errorBackoff(failures int) time.Duration {
+	d := k.conf.ErrorRefreshDuration
+	for i := 1; i < failures && d < k.conf.RefreshDuration; i++ {
+		d *= 2
+	}
+	return min(d, k.conf.RefreshDuration)
+}
+
+func (k *Keeper) fetchContest(ctx context.Context, src StandingsSource, ct Contest) (*Standings, error) {
+	st, err := src.FetchStandings(ctx, ct)
+	if err != nil {
+		return nil, err
+	}
+	for i := range st.Header.Tasks {
+		h := &st.Header.Tasks[i]
+		h.MaxScore = ct.TaskMaxScore(h.Name, k.conf.MaxScorePerTask)
+	}
+	err = st.SetRanking(k.conf.Ranking, *k.conf.PenaltyPerAttempt)
+	if err != nil {
+		return nil, fmt.Errorf("ranking standings: %w", err)
+	}
+	return st, nil
+}
+
+func (k *Keeper) filterContest(st *Standings) *Standings {
+	res := *st
+	res.Participants = make([]Participant, len(st.Participants))
+	for i, p := range st.Participants {
+		res.Participants[i] = k.teams.AssignTeam(p)
+	}
+	st = &res
+	if k.whitelist != nil {
+		st = st.FilterRegex(k.whitelist, FilterModeWhitelist)
+	}
+	if k.blacklist != nil {
+		st = st.FilterRegex(k.blacklist, FilterModeBlacklist)
+	}
+	st.ComputePlaces()
+	return st
+}
+
+func (k *Keeper) prepare(contests []ContestStatus) ([]*Standings, []string, error) {
+	sts := make([]*Standings, len(contests))
+	for i := range contests {
+		sts[i] = contests[i].Standings
+	}
+	sts, conflicts, err := k.identity.Resolve(k.logger, sts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving identities: %w", err)
+	}
+	for i, st := range sts {
+		if st == nil {
+			continue
+		}
+		sts[i] = k.filterContest(st)
+	}
+	return sts, conflicts, nil
+}
+
+func (k *Keeper) applyConfig() {
+	conf := k.store.Get()
+	if conf == k.seenConf {
+		return
+	}
+	k.seenConf = conf
+	if err := k.reconfigure(conf); err != nil {
+		k.logger.Error("cannot apply new config, keeping the old one", zap.Error(err))
+		return
+	}
+	k.logger.Info("applied new config")
+}
+
+// refresh fetches the contests which are due (or all of them if force is set), rebuilds the snapshots and
+// returns the time of the next scheduled fetch. Each contest is scheduled separately, so a failing contest
+// is retried with backoff without refetching the healthy ones.
+func (k *Keeper) refresh(ctx context.Context, force bool) time.Time {
+	k.applyConfig()
+	k.logger.Info("refreshing standings", zap.Bool("force", force))
+	var g errgroup.Group
+	refreshTime := time.Now()
+	for i := range k.contests {
+		c := &k.contests[i]
+		frozen := &k.frozen[i]
+		src := k.sources[i]
+		if !force && refreshTime.Before(c.nextFetch) {
+			continue
+		}
 		g.Go(func() error {
-			st, err := func() (*Standings, error) {
-				st, err := k.api.FetchStandings(ct)
-				if err != nil {
-					return nil, err
-				}
-				for i := range st.Participants {
-					st.Participants[i] = k.teams.AssignTeam(st.Participants[i])
-				}
-				if k.conf.LoginWhitelistRegex != nil {
-					st, err = st.FilterRegex(*k.conf.LoginWhitelistRegex, FilterModeWhitelist)
-					if err != nil {
-						return nil, err
-					}
+			start := time.Now()
+			st, err := k.fetchContest(ctx, src, c.Contest)
+			now := time.Now()
+			c.Latency = now.Sub(start)
+			if err != nil {
+				k.logger.Info("got error while refreshing standings", zap.Stringer("contest", c.Contest), zap.Error(err))
+				if c.Standings != nil {
+					k.logger.Warn("serving stale contest standings", zap.Stringer("contest", c.Contest), zap.Time("update_time", c.UpdateTime))
 				}
-				if k.conf.LoginBlacklistRegex != nil {
-					st, err = st.FilterRegex(*k.conf.LoginBlacklistRegex, FilterModeBlacklist)
-					if err != nil {
-						return nil, err
-					}
+				c.Err = err
+				c.ErrTime = now
+				c.failures++
+				// Do not come back earlier than the contest API asked, even if it exceeds the retry max backoff.
+				c.nextFetch = now.Add(max(k.errorBackoff(c.failures), retryAfter(err)))
+				return nil
+			}
+			c.Standings = st
+			c.UpdateTime = now
+			c.Err = nil
+			c.ErrTime = time.Time{}
+			c.failures = 0
+			c.nextFetch = now.Add(k.conf.RefreshDuration)
+			if c.Contest.FreezeTime != nil && now.Before(*c.Contest.FreezeTime) {
+				*frozen = *c
+				if err := storeFrozenState(frozen); err != nil {
+					k.logger.Error("cannot store frozen standings", zap.Stringer("contest", c.Contest), zap.Error(err))
 				}
-				return st, nil
-			}()
-			res[i] = st
-			if err != nil {
-				logger.Info("got error while refreshing standings", zap.Error(err))
 			}
-			return err
+			return nil
 		})
 	}
-	err := g.Wait()
-	var st *Standings
-	if err == nil {
-		st, err = MergeStandings(logger, res...)
+	_ = g.Wait()
+
+	k.mu.RLock()
+	prevSnap := k.snap
+	prevJurySnap := k.jurySnap
+	unfreezeGen := k.unfreezeGen
+	k.mu.RUnlock()
+
+	jurySnap := k.buildSnapshot(prevJurySnap, k.contests)
+	snap := jurySnap
+	if contests, freezeTime, ok := k.publicContests(); ok {
+		snap = k.buildSnapshot(prevSnap, contests)
+		snap.Frozen = true
+		snap.FreezeTime = freezeTime
+		jurySnap.Frozen = true
+		jurySnap.FreezeTime = freezeTime
+	}
+
+	k.mu.Lock()
+	if k.unfreezeGen != unfreezeGen {
+		// The contests were unfrozen while refreshing, so the public snapshot built above is outdated.
+		jurySnap.Frozen = false
+		jurySnap.FreezeTime = time.Time{}
+		snap = jurySnap
+	}
+	k.jurySnap = jurySnap
+	k.snap = snap
+	k.mu.Unlock()
+
+	next := time.Now().Add(k.conf.RefreshDuration)
+	for i := range k.contests {
+		if k.contests[i].nextFetch.Before(next) {
+			next = k.contests[i].nextFetch
+		}
+	}
+	return next
+}
+
+func (k *Keeper) publicContests() ([]ContestStatus, time.Time, bool) {
+	now := time.Now()
+	contests := append([]ContestStatus(nil), k.contests...)
+	var freezeTime time.Time
+	frozen := false
+	for i := range contests {
+		ct := contests[i].Contest
+		if !k.isFrozen(ct, now) {
+			continue
+		}
+		if !frozen || ct.FreezeTime.Before(freezeTime) {
+			freezeTime = *ct.FreezeTime
+		}
+		frozen = true
+		contests[i] = k.frozen[i]
+		if contests[i].Standings == nil {
+			// Do not reveal the current results, but show that the contest is missing.
+			k.logger.Warn("contest is frozen, but its standings as of the freeze time are not available", zap.Stringer("contest", ct))
+			contests[i].Err = ErrFrozenStandingsMissing
+			contests[i].ErrTime = now
+		}
+	}
+	return contests, freezeTime, frozen
+}
+
+func (k *Keeper) buildSnapshot(prev *Snapshot, contests []ContestStatus) *Snapshot {
+	snap := *prev
+	snap.Frozen = false
+	snap.FreezeTime = time.Time{}
+	now := time.Now()
+	if err := k.merge(&snap, contests); err != nil {
+		if snap.Standings != nil {
+			k.logger.Warn("serving stale standings", zap.Time("update_time", snap.UpdateTime))
+		}
+		prevSts := make([]*Standings, len(contests))
+		if len(snap.Contests) == len(prevSts) {
+			for i := range prevSts {
+				prevSts[i] = snap.Contests[i].Standings
+			}
+		}
+		snap.Contests = contestStatuses(contests, prevSts)
+		snap.Err = err
+		snap.ErrTime = now
+	} else {
+		snap.UpdateTime = now
+		snap.Err = nil
+		snap.ErrTime = time.Time{}
+	}
+	return &snap
+}
+
+func contestStatuses(contests []ContestStatus, sts []*Standings) []ContestStatus {
+	res := append([]ContestStatus(nil), contests...)
+	for i := range res {
+		res[i].Standings = sts[i]
+	}
+	return res
+}
+
+func (k *Keeper) merge(snap *Snapshot, contests []ContestStatus) error {
+	var errs []error
+	hasAny := false
+	for i := range contests {
+		c := &contests[i]
+		if c.Standings != nil {
+			hasAny = true
+		}
+		if c.Err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", c.Contest, c.Err))
+		}
+	}
+	if !hasAny && len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	sts, conflicts, err := k.prepare(contests)
+	if err != nil {
+		return err
+	}
+	st, err := MergeStandings(k.logger, sts...)
+	if err != nil {
+		return err
+	}
+	if k.agg != nil {
+		err = st.SetAggregation(k.agg)
+		if err != nil {
+			return fmt.Errorf("aggregating standings: %w", err)
+		}
 	}
-	k.st = st
-	k.err = err
-	k.fetched = true
-	k.fetchTime = time.Now()
-	return st, err
+	snap.Standings = st
+	snap.Conf = k.conf
+	snap.Contests = contestStatuses(contests, sts)
+	snap.Conflicts = conflicts
+	return nil
 }
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alex65536/yacontable/internal/fakecontest"
+	"go.uber.org/zap"
+)
+
+func mustGet(t *testing.T, get func() (*Snapshot, error)) *Snapshot {
+	t.Helper()
+	snap, err := get()
+	if err != nil {
+		t.Fatalf("getting snapshot: %v", err)
+	}
+	return snap
+}
+
+func hasLogin(snap *Snapshot, login string) bool {
+	return slices.Contains(participantLogins(snap.Standings), login)
+}
+
+func failAllAttempts(env *testEnv, id int) {
+	for i := 0; i < env.store.Get().Retry.MaxAttempts; i++ {
+		env.fake.EnqueueResponses(id, fakecontest.ServerError(http.StatusInternalServerError))
+	}
+}
+
+func TestKeeperKeepsStaleContest(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.refresh()
+	snap := mustGet(t, env.keeper.Get)
+	if snap.Stale() {
+		t.Fatal("fresh snapshot is stale")
+	}
+	if n := len(snap.Standings.Header.Tasks); n != 5 {
+		t.Fatalf("got %v tasks, want 5", n)
+	}
+
+	failAllAttempts(env, 1)
+	env.refresh()
+	snap = mustGet(t, env.keeper.Get)
+	if !snap.Stale() {
+		t.Error("snapshot with failed contest is not stale")
+	}
+	d1 := &snap.Contests[0]
+	if !d1.Stale() || d1.Standings == nil {
+		t.Errorf("failed contest: stale=%v, loaded=%v, want stale and loaded", d1.Stale(), d1.Standings != nil)
+	}
+	if snap.Contests[1].Stale() {
+		t.Error("healthy contest is stale")
+	}
+	// my-login-3 and my-login-4 participate only in D1, so they are kept only if the stale data is used.
+	if !hasLogin(snap, "my-login-3") || !hasLogin(snap, "my-login-4") {
+		t.Errorf("stale contest data was dropped, got participants %v", participantLogins(snap.Standings))
+	}
+	if n := len(snap.Standings.Header.Tasks); n != 5 {
+		t.Errorf("got %v tasks, want 5", n)
+	}
+
+	env.refresh()
+	snap = mustGet(t, env.keeper.Get)
+	if snap.Stale() {
+		t.Error("snapshot is stale after successful refresh")
+	}
+}
+
+func TestKeeperContestNeverLoaded(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests = append(conf.Contests, Contest{ID: 3, Tag: "D3"})
+	})
+	env.refresh()
+	snap := mustGet(t, env.keeper.Get)
+	d3 := &snap.Contests[2]
+	if d3.Err == nil || d3.Standings != nil {
+		t.Errorf("missing contest: err=%v, loaded=%v, want error and not loaded", d3.Err, d3.Standings != nil)
+	}
+	if n := len(snap.Standings.Header.Tasks); n != 5 {
+		t.Errorf("got %v tasks, want 5", n)
+	}
+}
+
+func TestKeeperAllContestsFailed(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests = []Contest{{ID: 3, Tag: "D3"}}
+	})
+	env.refresh()
+	if _, err := env.keeper.Get(); err == nil || errors.Is(err, ErrStandingsNotLoaded) {
+		t.Errorf("got error %v, want fetch error", err)
+	}
+}
+
+func TestKeeperRefreshesFailingContestOnly(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests = append(conf.Contests, Contest{ID: 3, Tag: "D3"})
+		conf.RefreshDuration = time.Hour
+		conf.ErrorRefreshDuration = 20 * time.Millisecond
+	})
+	// Contests 1 and 2 fit into a single page, contest 3 fails with 404 and is not retried within a refresh.
+	next := env.keeper.refresh(context.Background(), true)
+	if n := env.fake.Requests(); n != 3 {
+		t.Fatalf("got %v requests, want 3", n)
+	}
+	if d := time.Until(next); d > 20*time.Millisecond {
+		t.Errorf("next refresh in %v, want at most 20ms", d)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	next = env.keeper.refresh(context.Background(), false)
+	if n := env.fake.Requests(); n != 4 {
+		t.Errorf("got %v requests, want 4: only the failing contest must be refetched", n)
+	}
+	if d := time.Until(next); d < 20*time.Millisecond || d > 40*time.Millisecond {
+		t.Errorf("next refresh in %v, want backoff of 40ms", d)
+	}
+
+	env.keeper.refresh(context.Background(), false)
+	if n := env.fake.Requests(); n != 4 {
+		t.Errorf("got %v requests, want 4: no contests are due", n)
+	}
+}
+
+func TestKeeperHonorsRetryAfter(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.RefreshDuration = 10 * time.Second
+	})
+	env.fake.EnqueueResponses(1, fakecontest.RateLimited(60))
+	env.keeper.refresh(context.Background(), true)
+	if n := env.fake.Requests(); n != 2 {
+		t.Fatalf("got %v requests, want 2: the rate limited request must not be retried", n)
+	}
+	if d := time.Until(env.keeper.contests[0].nextFetch); d < 59*time.Second {
+		t.Errorf("rate limited contest is refetched in %v, want at least 60s", d)
+	}
+}
+
+func TestKeeperErrorBackoff(t *testing.T) {
+	k := &Keeper{conf: &Config{
+		RefreshDuration:      time.Minute,
+		ErrorRefreshDuration: time.Second,
+	}}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 3, want: 4 * time.Second},
+		{failures: 7, want: time.Minute},
+		{failures: 100, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := k.errorBackoff(tt.failures); got != tt.want {
+			t.Errorf("errorBackoff(%v) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestKeeperFreeze(t *testing.T) {
+	freeze1 := time.Now().Add(100 * time.Millisecond)
+	freeze2 := time.Now().Add(300 * time.Millisecond)
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests[0].FreezeTime = &freeze1
+		conf.Contests[1].FreezeTime = &freeze2
+	})
+	env.refresh()
+
+	time.Sleep(time.Until(freeze1))
+	env.fake.SetStandings(1, fakeStandings("late1"))
+	env.refresh()
+	pub := mustGet(t, env.keeper.Get)
+	jury := mustGet(t, env.keeper.GetJury)
+	if !pub.Frozen || hasLogin(pub, "late1") {
+		t.Errorf("public: frozen=%v, late1 shown=%v, want frozen and hidden", pub.Frozen, hasLogin(pub, "late1"))
+	}
+	if !jury.Frozen || !hasLogin(jury, "late1") {
+		t.Errorf("jury: frozen=%v, late1 shown=%v, want frozen and shown", jury.Frozen, hasLogin(jury, "late1"))
+	}
+
+	env.keeper.Unfreeze()
+	pub = mustGet(t, env.keeper.Get)
+	if pub.Frozen || !hasLogin(pub, "late1") {
+		t.Errorf("after unfreeze: frozen=%v, late1 shown=%v, want unfrozen and shown", pub.Frozen, hasLogin(pub, "late1"))
+	}
+	env.refresh()
+	pub = mustGet(t, env.keeper.Get)
+	if pub.Frozen || !hasLogin(pub, "late1") {
+		t.Errorf("after unfreeze and refresh: frozen=%v, late1 shown=%v, want unfrozen and shown", pub.Frozen, hasLogin(pub, "late1"))
+	}
+
+	// Unfreezing the first contest must not prevent the second one from freezing.
+	time.Sleep(time.Until(freeze2))
+	env.fake.SetStandings(2, fakeStandings("late2"))
+	env.refresh()
+	pub = mustGet(t, env.keeper.Get)
+	if !pub.Frozen || hasLogin(pub, "late2") || !hasLogin(pub, "late1") {
+		t.Errorf("after second freeze: frozen=%v, late1 shown=%v, late2 shown=%v, want frozen with only late1 shown",
+			pub.Frozen, hasLogin(pub, "late1"), hasLogin(pub, "late2"))
+	}
+}
+
+func TestKeeperFreezeTimeChanged(t *testing.T) {
+	freeze := time.Now().Add(100 * time.Millisecond)
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests[0].FreezeTime = &freeze
+	})
+	env.refresh()
+	time.Sleep(time.Until(freeze))
+	env.keeper.Unfreeze()
+
+	// Moving the freeze time freezes the unfrozen contest again.
+	newFreeze := freeze.Add(-time.Millisecond)
+	err := env.store.Update(func(conf *Config) error {
+		conf.Contests[0].FreezeTime = &newFreeze
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.fake.SetStandings(1, fakeStandings("late1"))
+	env.refresh()
+	pub := mustGet(t, env.keeper.Get)
+	if !pub.Frozen || hasLogin(pub, "late1") {
+		t.Errorf("frozen=%v, late1 shown=%v, want frozen and hidden", pub.Frozen, hasLogin(pub, "late1"))
+	}
+}
+
+func TestKeeperFrozenStandingsAfterRestart(t *testing.T) {
+	freeze := time.Now().Add(100 * time.Millisecond)
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests[0].FreezeTime = &freeze
+	})
+	env.refresh()
+	time.Sleep(time.Until(freeze))
+	env.fake.SetStandings(1, fakeStandings("late1"))
+
+	// The new keeper has never fetched the contest before the freeze, so it must load the stored standings.
+	keeper, err := NewKeeper(zap.NewNop(), env.store, env.api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.keeper = keeper
+	env.refresh()
+	pub := mustGet(t, env.keeper.Get)
+	if !pub.Frozen || hasLogin(pub, "late1") || !hasLogin(pub, "my-login-3") {
+		t.Errorf("frozen=%v, late1 shown=%v, frozen data shown=%v, want frozen data only",
+			pub.Frozen, hasLogin(pub, "late1"), hasLogin(pub, "my-login-3"))
+	}
+	if pub.Stale() {
+		t.Error("restored frozen standings are stale")
+	}
+}
+
+func TestKeeperUnfreezeAfterRestart(t *testing.T) {
+	freeze := time.Now().Add(100 * time.Millisecond)
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests[0].FreezeTime = &freeze
+	})
+	env.refresh()
+	time.Sleep(time.Until(freeze))
+	env.fake.SetStandings(1, fakeStandings("late1"))
+	env.refresh()
+	env.keeper.Unfreeze()
+
+	keeper, err := NewKeeper(zap.NewNop(), env.store, env.api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.keeper = keeper
+	env.refresh()
+	pub := mustGet(t, env.keeper.Get)
+	if pub.Frozen || !hasLogin(pub, "late1") {
+		t.Errorf("after restart: frozen=%v, late1 shown=%v, want unfrozen and shown", pub.Frozen, hasLogin(pub, "late1"))
+	}
+
+	// The stored unfreeze is bound to the freeze time, so moving it freezes the contest again.
+	newFreeze := freeze.Add(-time.Millisecond)
+	err = env.store.Update(func(conf *Config) error {
+		conf.Contests[0].FreezeTime = &newFreeze
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keeper, err = NewKeeper(zap.NewNop(), env.store, env.api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.keeper = keeper
+	env.refresh()
+	pub = mustGet(t, env.keeper.Get)
+	if !pub.Frozen || hasLogin(pub, "late1") {
+		t.Errorf("after moving freeze time: frozen=%v, late1 shown=%v, want frozen and hidden", pub.Frozen, hasLogin(pub, "late1"))
+	}
+}
+
+func TestKeeperFrozenStandingsMissing(t *testing.T) {
+	freeze := time.Now().Add(-time.Hour)
+	env := newTestEnv(t, func(conf *Config) {
+		conf.Contests[0].FreezeTime = &freeze
+	})
+	env.refresh()
+	pub := mustGet(t, env.keeper.Get)
+	if !pub.Stale() || !errors.Is(pub.Contests[0].Err, ErrFrozenStandingsMissing) {
+		t.Errorf("stale=%v, contest error=%v, want contest without frozen standings reported", pub.Stale(), pub.Contests[0].Err)
+	}
+	if hasLogin(pub, "my-login-3") {
+		t.Error("current results of the frozen contest are revealed")
+	}
+	jury := mustGet(t, env.keeper.GetJury)
+	if !hasLogin(jury, "my-login-3") {
+		t.Error("jury standings miss the frozen contest")
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+)
+
+type NormalizationMode string
+
+const (
+	NormalizationNone   NormalizationMode = ""
+	NormalizationMax    NormalizationMode = "max"
+	NormalizationBest   NormalizationMode = "best"
+	NormalizationZScore NormalizationMode = "zscore"
+)
+
+type normalization struct {
+	offset []float64
+	scale  []float64
+}
+
+func (n *normalization) apply(contestScores []float64) []float64 {
+	res := make([]float64, len(contestScores))
+	for c, v := range contestScores {
+		if c < len(n.scale) {
+			res[c] = (v - n.offset[c]) * n.scale[c]
+		}
+	}
+	return res
+}
+
+func contestMaxScores(s *Standings, numContests int) ([]float64, error) {
+	res := make([]float64, numContests)
+	for _, h := range s.Header.Tasks {
+		if h.MaxScore == nil {
+			return nil, fmt.Errorf("task %q has no max score", h.Title)
+		}
+		if h.Contest < numContests {
+			res[h.Contest] += *h.MaxScore
+		}
+	}
+	return res, nil
+}
+
+func newNormalization(mode NormalizationMode, s *Standings, scores [][]float64, numContests int) (*normalization, error) {
+	res := &normalization{
+		offset: make([]float64, numContests),
+		scale:  make([]float64, numContests),
+	}
+	safeInv := func(v float64) float64 {
+		if v <= 0 {
+			return 0
+		}
+		return 1.0 / v
+	}
+	switch mode {
+	case NormalizationMax:
+		maxScores, err := contestMaxScores(s, numContests)
+		if err != nil {
+			return nil, fmt.Errorf("normalizing by max score: %w", err)
+		}
+		for c, v := range maxScores {
+			res.scale[c] = safeInv(v)
+		}
+	case NormalizationBest:
+		for c := range res.scale {
+			best := 0.0
+			for _, p := range scores {
+				best = max(best, p[c])
+			}
+			res.scale[c] = safeInv(best)
+		}
+	case NormalizationZScore:
+		if len(scores) == 0 {
+			return res, nil
+		}
+		for c := range res.scale {
+			mean := 0.0
+			for _, p := range scores {
+				mean += p[c]
+			}
+			mean /= float64(len(scores))
+			variance := 0.0
+			for _, p := range scores {
+				variance += (p[c] - mean) * (p[c] - mean)
+			}
+			variance /= float64(len(scores))
+			res.offset[c] = mean
+			res.scale[c] = safeInv(math.Sqrt(variance))
+		}
+	default:
+		return nil, fmt.Errorf("unknown normalization mode %q", mode)
+	}
+	return res, nil
+}
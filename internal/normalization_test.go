@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewNormalization(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	withMax := &Standings{Header: Header{Tasks: []TaskHeader{
+		{Title: "A", Contest: 0, MaxScore: f(100)},
+		{Title: "B", Contest: 0, MaxScore: f(100)},
+		{Title: "C", Contest: 1, MaxScore: f(50)},
+	}}}
+	withoutMax := &Standings{Header: Header{Tasks: []TaskHeader{
+		{Title: "A", Contest: 0, MaxScore: f(100)},
+		{Title: "C", Contest: 1},
+	}}}
+	zeroMax := &Standings{Header: Header{Tasks: []TaskHeader{
+		{Title: "A", Contest: 0, MaxScore: f(100)},
+		{Title: "C", Contest: 1, MaxScore: f(0)},
+	}}}
+	scores := [][]float64{{200, 10}, {100, 30}, {0, 20}}
+
+	tests := []struct {
+		name    string
+		mode    NormalizationMode
+		s       *Standings
+		scores  [][]float64
+		input   []float64
+		want    []float64
+		wantErr bool
+	}{
+		{name: "max", mode: NormalizationMax, s: withMax, scores: scores, input: []float64{100, 50}, want: []float64{0.5, 1}},
+		{name: "max without max score", mode: NormalizationMax, s: withoutMax, scores: scores, wantErr: true},
+		{name: "max with zero max score", mode: NormalizationMax, s: zeroMax, scores: scores, input: []float64{50, 10}, want: []float64{0.5, 0}},
+		{name: "best", mode: NormalizationBest, s: withoutMax, scores: scores, input: []float64{100, 30}, want: []float64{0.5, 1}},
+		{name: "best with no positive scores", mode: NormalizationBest, s: withoutMax, scores: [][]float64{{0, 0}}, input: []float64{0, 0}, want: []float64{0, 0}},
+		{name: "zscore", mode: NormalizationZScore, s: withoutMax, scores: scores, input: []float64{200, 20}, want: []float64{1.224744871391589, 0}},
+		{name: "zscore without spread", mode: NormalizationZScore, s: withoutMax, scores: [][]float64{{5, 5}, {5, 5}}, input: []float64{5, 7}, want: []float64{0, 0}},
+		{name: "zscore without participants", mode: NormalizationZScore, s: withoutMax, input: []float64{5, 7}, want: []float64{0, 0}},
+		{name: "unknown mode", mode: "median", s: withMax, scores: scores, wantErr: true},
+	}
+	for _, tt := range tests {
+		norm, err := newNormalization(tt.mode, tt.s, tt.scores, 2)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: got error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got := norm.apply(tt.input)
+		for c := range tt.want {
+			if math.Abs(got[c]-tt.want[c]) > 1e-9 {
+				t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNormalizationApplyIgnoresExtraContests(t *testing.T) {
+	norm := &normalization{offset: []float64{0}, scale: []float64{2}}
+	got := norm.apply([]float64{3, 4})
+	if got[0] != 6 || got[1] != 0 {
+		t.Errorf("got %v, want [6 0]", got)
+	}
+}
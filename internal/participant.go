@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+var errParticipantNotFound = errors.New("participant not found")
+
+type participantContest struct {
+	Contest         Contest
+	Tasks           []TaskHeader
+	Result          *Participant
+	NumParticipants int
+}
+
+type participantStat struct {
+	Value  float64
+	Median float64
+	Top    float64
+}
+
+func findParticipant(st *Standings, login string) *Participant {
+	for i := range st.Participants {
+		if st.Participants[i].Login == login {
+			return &st.Participants[i]
+		}
+	}
+	return nil
+}
+
+func calcParticipantStat(st *Standings, pp *Participant, value func(p *Participant) float64) participantStat {
+	values := make([]float64, len(st.Participants))
+	for i := range st.Participants {
+		values[i] = value(&st.Participants[i])
+	}
+	slices.Sort(values)
+	res := participantStat{
+		Value: value(pp),
+	}
+	if n := len(values); n != 0 {
+		res.Top = values[n-1]
+		if n%2 == 1 {
+			res.Median = values[n/2]
+		} else {
+			res.Median = (values[n/2-1] + values[n/2]) / 2.0
+		}
+	}
+	return res
+}
+
+func (p *Presenter) doBuildParticipant(login string, jury bool) ([]byte, error) {
+	type state struct {
+		Conf            *Config
+		Participant     *Participant
+		Standings       *Standings
+		NumParticipants int
+		Stat            participantStat
+		Contests        []participantContest
+		Snapshot        *Snapshot
+	}
+
+	snap, err := p.getSnapshot(jury)
+	if err != nil {
+		return nil, fmt.Errorf("getting statements: %w", err)
+	}
+	pp := findParticipant(snap.Standings, login)
+	if pp == nil {
+		return nil, errParticipantNotFound
+	}
+
+	value := func(p *Participant) float64 {
+		return p.Total
+	}
+	if snap.Standings.IsICPC() {
+		value = func(p *Participant) float64 {
+			return float64(p.Solved)
+		}
+	}
+
+	var contests []participantContest
+	for _, c := range snap.Contests {
+		if c.Standings == nil {
+			continue
+		}
+		contests = append(contests, participantContest{
+			Contest:         c.Contest,
+			Tasks:           c.Standings.Header.Tasks,
+			Result:          findParticipant(c.Standings, login),
+			NumParticipants: len(c.Standings.Participants),
+		})
+	}
+
+	var b bytes.Buffer
+	err = p.t.ExecuteTemplate(&b, "participant.html", &state{
+		Conf:            p.renderConf(snap),
+		Participant:     pp,
+		Standings:       snap.Standings,
+		NumParticipants: len(snap.Standings.Participants),
+		Stat:            calcParticipantStat(snap.Standings, pp, value),
+		Contests:        contests,
+		Snapshot:        snap,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (p *Presenter) ServeParticipant(w http.ResponseWriter, req *http.Request) {
+	p.logger.Info("get participant", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "use GET method")
+		return
+	}
+	login := req.URL.Query().Get("login")
+	if p.conf().HideLogins || login == "" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "participant not found")
+		return
+	}
+	b, err := p.doBuildParticipant(login, isJury(req))
+	if errors.Is(err, errParticipantNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "participant not found")
+		return
+	}
+	if errors.Is(err, ErrStandingsNotLoaded) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "standings are not loaded yet, please try again later")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		p.logger.Error("error serving request", zap.Error(err))
+		_, _ = io.WriteString(w, "got error: "+err.Error())
+		return
+	}
+	_, _ = w.Write(b)
+}
@@ -2,13 +2,14 @@ package internal
 
 import (
 	"bytes"
-	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -17,10 +18,25 @@ import (
 
 type Presenter struct {
 	k      *Keeper
-	ctx    context.Context
+	auth   *Authorizer
 	logger *zap.Logger
 	t      *template.Template
-	conf   *Config
+	store  *ConfigStore
+}
+
+func (p *Presenter) conf() *Config {
+	return p.store.Get()
+}
+
+// renderConf returns the config to render the snapshot with. The teams and the ranking are the ones the snapshot
+// was built with, and the display settings are the current ones, so that the admin panel changes apply immediately.
+func (p *Presenter) renderConf(snap *Snapshot) *Config {
+	cur := p.conf()
+	res := *snap.Conf
+	res.HideLogins = cur.HideLogins
+	res.DisplayNames = cur.DisplayNames
+	res.DisplayTeams = cur.DisplayTeams
+	return &res
 }
 
 func getScoreColor(score float64) string {
@@ -47,60 +63,86 @@ func getScoreColor(score float64) string {
 	return fmt.Sprintf("#%02x%02x%02x", int(math.Round(r*255.0)), int(math.Round(g*255.0)), int(math.Round(b*255.0)))
 }
 
-func NewPresenter(ctx context.Context, logger *zap.Logger, k *Keeper, conf *Config) (*Presenter, error) {
+func NewPresenter(logger *zap.Logger, k *Keeper, auth *Authorizer, store *ConfigStore) (*Presenter, error) {
 	funcMap := template.FuncMap{
-		"inc": func(i int) int {
-			return i + 1
-		},
-		"supportsColor": func() bool {
-			return conf.MaxScorePerTask != nil
-		},
-		"supportsFullScores": func() bool {
-			return conf.MaxScorePerTask != nil
+		"isICPC": func(conf *Config) bool {
+			return conf.Ranking == RankingModeICPC
 		},
-		"supportsLogins": func() bool {
+		"supportsLogins": func(conf *Config) bool {
 			return !conf.HideLogins
 		},
-		"supportsNames": func() bool {
+		"supportsNames": func(conf *Config) bool {
 			return conf.DisplayNames
 		},
-		"supportsTeams": func() bool {
+		"supportsTeams": func(conf *Config) bool {
 			return conf.DisplayTeams
 		},
-		"showFilter": func() bool {
+		"showFilter": func(conf *Config) bool {
 			return !conf.HideLogins || conf.DisplayTeams
 		},
-		"calcColor": func(count int, score float64) string {
-			return getScoreColor(score / (*conf.MaxScorePerTask * float64(count)))
+		"calcTaskColor": func(h TaskHeader, score float64) string {
+			if h.MaxScore == nil || *h.MaxScore <= 0 {
+				return ""
+			}
+			return getScoreColor(score / *h.MaxScore)
+		},
+		"calcTotalColor": func(st *Standings, score float64) string {
+			maxTotal, ok := st.MaxTotal()
+			if !ok || maxTotal <= 0 {
+				return ""
+			}
+			return getScoreColor(score / maxTotal)
+		},
+		"formatTime": func(t time.Time) string {
+			return t.Format("15:04")
+		},
+		"formatDuration": func(d time.Duration) string {
+			if d < time.Millisecond {
+				return d.Round(time.Microsecond).String()
+			}
+			return d.Round(time.Millisecond).String()
 		},
-		"teamIDtoName": func(teamID int) string {
+		"teamIDtoName": func(conf *Config, teamID int) string {
 			if teamID < 0 || teamID >= len(conf.Teams) {
 				return "?"
 			}
 			return conf.Teams[teamID].Name
 		},
 	}
-	t, err := template.New("standings").Funcs(funcMap).ParseFiles("./data/standings.html")
+	if err := store.Get().TeamRanking.Validate(); err != nil {
+		return nil, fmt.Errorf("validating team ranking: %w", err)
+	}
+	t, err := template.New("standings").Funcs(funcMap).ParseFiles("./data/standings.html", "./data/participant.html", "./data/teams.html", "./data/admin.html")
 	if err != nil {
 		return nil, fmt.Errorf("parsing template: %w", err)
 	}
 	return &Presenter{
 		k:      k,
-		ctx:    ctx,
+		auth:   auth,
 		logger: logger,
 		t:      t,
-		conf:   conf,
+		store:  store,
 	}, nil
 }
 
 func (p *Presenter) calcNumFullScores(st *Standings) []int {
-	if p.conf.MaxScorePerTask == nil {
+	if !st.HasMaxScores() && !st.IsICPC() {
 		return nil
 	}
 	res := make([]int, len(st.Header.Tasks))
+	for i, h := range st.Header.Tasks {
+		if !st.IsICPC() && h.MaxScore == nil {
+			res[i] = -1
+		}
+	}
 	for _, pp := range st.Participants {
 		for i, t := range pp.Tasks {
-			if t.Score == *p.conf.MaxScorePerTask {
+			h := st.Header.Tasks[i]
+			if st.IsICPC() {
+				if t.Accepted {
+					res[i]++
+				}
+			} else if h.MaxScore != nil && t.Score == *h.MaxScore {
 				res[i]++
 			}
 		}
@@ -108,34 +150,83 @@ func (p *Presenter) calcNumFullScores(st *Standings) []int {
 	return res
 }
 
-func (p *Presenter) doBuildTemplate(prefix string, teamID int) ([]byte, error) {
+type filter struct {
+	prefix      string
+	teamID      int
+	localPlaces bool
+	jury        bool
+}
+
+func parseFilter(req *http.Request, conf *Config) filter {
+	query := req.URL.Query()
+	prefix := query.Get("prefix")
+	if conf.HideLogins {
+		prefix = ""
+	}
+	teamID := -1
+	if conf.DisplayTeams {
+		if teamStr := query.Get("team"); teamStr != "" {
+			if teamVal, err := strconv.ParseInt(teamStr, 10, 0); err == nil && 0 <= int(teamVal) && int(teamVal) < len(conf.Teams) {
+				teamID = int(teamVal)
+			}
+		}
+	}
+	return filter{
+		prefix:      prefix,
+		teamID:      teamID,
+		localPlaces: query.Get("place") == "local",
+		jury:        isJury(req),
+	}
+}
+
+func (f filter) apply(st *Standings) *Standings {
+	if f.prefix != "" {
+		st = st.FilterPrefix(f.prefix, FilterModeWhitelist)
+	}
+	if f.teamID != -1 {
+		st = st.FilterTeam(f.teamID)
+	}
+	if f.localPlaces && (f.prefix != "" || f.teamID != -1) {
+		st.ComputePlaces()
+	}
+	return st
+}
+
+func (p *Presenter) doBuildTemplate(req *http.Request) ([]byte, error) {
 	type state struct {
-		Prefix     string
-		TeamID     int
-		Standings  *Standings
-		FullScores []int
-		TeamNames  []string
+		Conf          *Config
+		Prefix        string
+		TeamID        int
+		LocalPlaces   bool
+		Standings     *Standings
+		FullScores    []int
+		TeamNames     []string
+		Snapshot      *Snapshot
+		Jury          bool
+		NotAuthorized bool
 	}
 
-	st, err := p.k.Get(p.ctx, p.logger)
+	snap, err := p.getSnapshot(isJury(req))
 	if err != nil {
 		return nil, fmt.Errorf("getting statements: %w", err)
 	}
-	if prefix != "" {
-		st = st.FilterPrefix(prefix, FilterModeWhitelist)
-	}
-	if teamID != -1 {
-		st = st.FilterTeam(teamID)
-	}
+	conf := p.renderConf(snap)
+	f := parseFilter(req, conf)
+	st := f.apply(snap.Standings)
 	var b bytes.Buffer
 	err = p.t.ExecuteTemplate(&b, "standings.html", &state{
-		Prefix:     prefix,
-		TeamID:     teamID,
-		Standings:  st,
-		FullScores: p.calcNumFullScores(st),
-		TeamNames: goutil.Map(p.conf.Teams, func(t TeamConfig) string {
+		Conf:        conf,
+		Prefix:      f.prefix,
+		TeamID:      f.teamID,
+		LocalPlaces: f.localPlaces,
+		Standings:   st,
+		FullScores:  p.calcNumFullScores(st),
+		TeamNames: goutil.Map(conf.Teams, func(t TeamConfig) string {
 			return t.Name
 		}),
+		Snapshot:      snap,
+		Jury:          f.jury,
+		NotAuthorized: p.notAuthorized(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("building template: %w", err)
@@ -155,20 +246,17 @@ func (p *Presenter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		_, _ = io.WriteString(w, "what are you doing here?")
 		return
 	}
-	query := req.URL.Query()
-	prefix := query.Get("prefix")
-	if p.conf.HideLogins {
-		prefix = ""
+	b, err := p.doBuildTemplate(req)
+	if err != nil && p.notAuthorized() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "contest API is not authorized yet, please ask the administrator to connect Yandex account")
+		return
 	}
-	teamID := -1
-	if p.conf.DisplayTeams {
-		if teamStr := query.Get("team"); teamStr != "" {
-			if teamVal, err := strconv.ParseInt(teamStr, 10, 0); err == nil && 0 <= int(teamVal) && int(teamVal) < len(p.conf.Teams) {
-				teamID = int(teamVal)
-			}
-		}
+	if errors.Is(err, ErrStandingsNotLoaded) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "standings are not loaded yet, please try again later")
+		return
 	}
-	b, err := p.doBuildTemplate(prefix, teamID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		p.logger.Error("error serving request", zap.Error(err))
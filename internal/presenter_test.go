@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func serve(h http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func decodeJSONStandings(t *testing.T, w *httptest.ResponseRecorder) *jsonStandings {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	var res jsonStandings
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decoding json: %v", err)
+	}
+	return &res
+}
+
+func TestPresenterJSON(t *testing.T) {
+	env := newTestEnv(t, nil)
+	p := env.presenter(t)
+
+	w := serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %v before the first refresh, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+
+	env.refresh()
+	res := decodeJSONStandings(t, serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings"))
+	if res.Stale || len(res.Contests) != 2 || !res.Contests[0].Loaded || !res.Contests[1].Loaded {
+		t.Errorf("unexpected status: stale=%v, contests=%+v", res.Stale, res.Contests)
+	}
+	titles := make([]string, len(res.Header.Tasks))
+	for i, h := range res.Header.Tasks {
+		titles[i] = h.Title
+	}
+	if want := []string{"D1-A", "D1-B", "D1-C", "D2-A", "D2-B"}; !slices.Equal(titles, want) {
+		t.Errorf("got tasks %v, want %v", titles, want)
+	}
+
+	type row struct {
+		login string
+		rank  int
+		total float64
+	}
+	want := []row{
+		{"my-login-1", 1, 390.5},
+		{"my-login-2", 2, 360},
+		{"my-login-3", 3, 160},
+		{"my-login-5", 4, 30},
+		{"my-login-4", 5, 20},
+	}
+	var got []row
+	for _, pp := range res.Participants {
+		got = append(got, row{pp.Login, pp.Rank, pp.Total})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got participants %v, want %v", got, want)
+	}
+
+	// Filtering keeps the global places.
+	res = decodeJSONStandings(t, serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings?prefix=my-login-3"))
+	if len(res.Participants) != 1 || res.Participants[0].Rank != 3 {
+		t.Errorf("got filtered participants %+v, want my-login-3 with rank 3", res.Participants)
+	}
+	res = decodeJSONStandings(t, serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings?prefix=my-login-3&place=local"))
+	if len(res.Participants) != 1 || res.Participants[0].Rank != 1 {
+		t.Errorf("got filtered participants %+v, want my-login-3 with local rank 1", res.Participants)
+	}
+}
+
+func TestPresenterJSONICPC(t *testing.T) {
+	noPenalty := 0
+	tests := []struct {
+		name        string
+		penalty     *int
+		wantPenalty [2]int
+	}{
+		// my-login-1: 44 + (74 + 20) + (253 + 40); my-login-2: (267 + 20) + (246 + 20) + (11 + 40).
+		{name: "default penalty", wantPenalty: [2]int{431, 604}},
+		{name: "no penalty", penalty: &noPenalty, wantPenalty: [2]int{371, 524}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(conf *Config) {
+				conf.Ranking = RankingModeICPC
+				conf.PenaltyPerAttempt = tt.penalty
+			})
+			p := env.presenter(t)
+			env.refresh()
+			res := decodeJSONStandings(t, serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings"))
+			if res.Ranking != RankingModeICPC {
+				t.Errorf("got ranking %q, want %q", res.Ranking, RankingModeICPC)
+			}
+			if len(res.Participants) < 2 {
+				t.Fatalf("got %v participants", len(res.Participants))
+			}
+			for i, login := range []string{"my-login-1", "my-login-2"} {
+				pp := res.Participants[i]
+				if pp.Login != login || pp.Solved != 3 || pp.Penalty != tt.wantPenalty[i] {
+					t.Errorf("got %v with %v solved and penalty %v at place %v, want %v with 3 and %v",
+						pp.Login, pp.Solved, pp.Penalty, i+1, login, tt.wantPenalty[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPresenterExport(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.DisplayNames = true
+	})
+	p := env.presenter(t)
+	env.refresh()
+
+	w := serve(p.ExportHandler(ExportFormatCSV), "/export.csv")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ExportFormatCSV.ContentType {
+		t.Errorf("got content type %q, want %q", ct, ExportFormatCSV.ContentType)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing csv: %v", err)
+	}
+	want := [][]string{
+		{"Rank", "Login", "Name", "D1-A", "D1-B", "D1-C", "D2-A", "D2-B", "Total"},
+		{"1", "my-login-1", "Alice", "100", "100", "40", "100", "50.5", "390.5"},
+		{"2", "my-login-2", "Bob", "100", "60", "0", "100", "100", "360"},
+		{"3", "my-login-3", "Carol", "100", "60", "0", "0", "0", "160"},
+		{"4", "my-login-5", "Eve", "0", "0", "0", "30", "0", "30"},
+		{"5", "my-login-4", "Dave", "20", "0", "0", "0", "0", "20"},
+	}
+	if !slices.EqualFunc(records, want, slices.Equal[[]string]) {
+		t.Errorf("got csv %v, want %v", records, want)
+	}
+
+	w = serve(p.ExportHandler(ExportFormatTSV), "/export.tsv?bom=1&prefix=my-login-4")
+	wantTSV := utf8BOM + "Rank\tLogin\tName\tD1-A\tD1-B\tD1-C\tD2-A\tD2-B\tTotal\n" +
+		"5\tmy-login-4\tDave\t20\t0\t0\t0\t0\t20\n"
+	if got := w.Body.String(); got != wantTSV {
+		t.Errorf("got tsv %q, want %q", got, wantTSV)
+	}
+}
+
+func TestPresenterHTML(t *testing.T) {
+	env := newTestEnv(t, nil)
+	p := env.presenter(t)
+	env.refresh()
+	w := serve(p, "/")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	for _, s := range []string{"my-login-1", "D2-B", "390.50"} {
+		if !strings.Contains(w.Body.String(), s) {
+			t.Errorf("page does not contain %q", s)
+		}
+	}
+}
+
+func TestPresenterConflicts(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		// Both logins participate in D2, so they conflict there.
+		conf.Aliases = map[string]string{"my-login-5": "my-login-1"}
+		conf.HideLogins = true
+	})
+	p := env.presenter(t)
+	env.refresh()
+
+	res := decodeJSONStandings(t, serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings"))
+	if len(res.Conflicts) != 0 {
+		t.Errorf("public standings reveal conflicts %q", res.Conflicts)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/standings", nil)
+	p.ServeJSON(w, req.WithContext(context.WithValue(req.Context(), juryKey{}, true)))
+	res = decodeJSONStandings(t, w)
+	if len(res.Conflicts) != 1 || !strings.Contains(res.Conflicts[0], `"my-login-5"`) {
+		t.Errorf("got jury conflicts %q, want the conflict of my-login-5", res.Conflicts)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	p.ServeAdmin(w, req.WithContext(context.WithValue(req.Context(), adminKey{}, true)))
+	if !strings.Contains(w.Body.String(), "both resolve to &#34;my-login-1&#34;") {
+		t.Errorf("admin page does not show the conflict")
+	}
+}
+
+func TestPresenterConfigChangedAfterRefresh(t *testing.T) {
+	env := newTestEnv(t, func(conf *Config) {
+		conf.DisplayTeams = true
+		conf.Teams = []TeamConfig{
+			{Name: "Red", Logins: []string{"my-login-1", "my-login-2"}},
+			{Name: "Blue", Patterns: []string{"^my-login-[345]$"}},
+		}
+	})
+	p := env.presenter(t)
+	env.refresh()
+
+	// The teams and the ranking change, but the standings are not rebuilt yet.
+	err := env.store.Update(func(conf *Config) error {
+		conf.Teams[0], conf.Teams[1] = conf.Teams[1], conf.Teams[0]
+		conf.Ranking = RankingModeICPC
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updating config: %v", err)
+	}
+
+	res := decodeJSONStandings(t, serve(http.HandlerFunc(p.ServeJSON), "/api/v1/standings"))
+	if res.Ranking != RankingModeScore {
+		t.Errorf("got ranking %q, want %q", res.Ranking, RankingModeScore)
+	}
+	if len(res.Participants) == 0 || res.Participants[0].Team != "Red" {
+		t.Errorf("got participants %+v, want my-login-1 in team Red", res.Participants)
+	}
+
+	w := serve(p.ExportHandler(ExportFormatCSV), "/export.csv?prefix=my-login-1")
+	want := "Rank,Login,Team,D1-A,D1-B,D1-C,D2-A,D2-B,Total\n" +
+		"1,my-login-1,Red,100,100,40,100,50.5,390.5\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got csv %q, want %q", got, want)
+	}
+
+	w = serve(p, "/")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "Penalty") {
+		t.Errorf("page shows the ICPC columns for score standings")
+	}
+}
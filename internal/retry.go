@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+type ApiErrorKind int
+
+const (
+	ApiErrorTemporary ApiErrorKind = iota
+	ApiErrorRateLimit
+	ApiErrorAuth
+	ApiErrorPermanent
+)
+
+func (k ApiErrorKind) String() string {
+	switch k {
+	case ApiErrorTemporary:
+		return "temporary"
+	case ApiErrorRateLimit:
+		return "rate_limit"
+	case ApiErrorAuth:
+		return "auth"
+	case ApiErrorPermanent:
+		return "permanent"
+	default:
+		return "unknown"
+	}
+}
+
+func (k ApiErrorKind) Retryable() bool {
+	return k == ApiErrorTemporary || k == ApiErrorRateLimit
+}
+
+type ApiError struct {
+	Kind       ApiErrorKind
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ApiError) Error() string {
+	switch e.Kind {
+	case ApiErrorAuth:
+		return fmt.Sprintf("contest API rejected authorization: %v", e.Err)
+	case ApiErrorRateLimit:
+		return fmt.Sprintf("contest API rate limit exceeded: %v", e.Err)
+	default:
+		return e.Err.Error()
+	}
+}
+
+func (e *ApiError) Unwrap() error {
+	return e.Err
+}
+
+func classifyStatus(rsp *http.Response, err error) *ApiError {
+	res := &ApiError{
+		StatusCode: rsp.StatusCode,
+		Err:        err,
+	}
+	switch {
+	case rsp.StatusCode == http.StatusUnauthorized || rsp.StatusCode == http.StatusForbidden:
+		res.Kind = ApiErrorAuth
+	case rsp.StatusCode == http.StatusTooManyRequests:
+		res.Kind = ApiErrorRateLimit
+		res.RetryAfter = parseRetryAfter(rsp.Header.Get("Retry-After"))
+	case rsp.StatusCode == http.StatusRequestTimeout || rsp.StatusCode >= 500:
+		res.Kind = ApiErrorTemporary
+		res.RetryAfter = parseRetryAfter(rsp.Header.Get("Retry-After"))
+	default:
+		res.Kind = ApiErrorPermanent
+	}
+	return res
+}
+
+func classifyTransportError(err error) *ApiError {
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &ApiError{Kind: ApiErrorPermanent, Err: err}
+	case isTokenRevoked(err) || errors.Is(err, ErrNotAuthorized):
+		return &ApiError{Kind: ApiErrorAuth, Err: err}
+	case errors.As(err, &retrieveErr) && retrieveErr.Response != nil:
+		// The token endpoint failed, but the token is not revoked, so classify it as any other response.
+		return classifyStatus(retrieveErr.Response, err)
+	default:
+		return &ApiError{Kind: ApiErrorTemporary, Err: err}
+	}
+}
+
+func classifyBodyError(err error) *ApiError {
+	var netErr net.Error
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.As(err, &netErr) {
+		return &ApiError{Kind: ApiErrorTemporary, Err: err}
+	}
+	return &ApiError{Kind: ApiErrorPermanent, Err: err}
+}
+
+func parseRetryAfter(val string) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(val, 10, 64); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retryAfter returns the delay the contest API asked to wait before the next request, or zero if there is none.
+func retryAfter(err error) time.Duration {
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+func (c *RetryConfig) backoff(attempt int) time.Duration {
+	d := float64(c.BaseBackoff) * math.Pow(2.0, float64(attempt-1))
+	if d > float64(c.MaxBackoff) {
+		d = float64(c.MaxBackoff)
+	}
+	d *= 1.0 + *c.Jitter*(2.0*rand.Float64()-1.0)
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+func withRetry[T any](ctx context.Context, logger *zap.Logger, c *RetryConfig, f func(ctx context.Context) (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := f(ctx)
+		if err == nil {
+			return res, nil
+		}
+		var apiErr *ApiError
+		if !errors.As(err, &apiErr) {
+			apiErr = &ApiError{Kind: ApiErrorPermanent, Err: err}
+		}
+		switch apiErr.Kind {
+		case ApiErrorAuth:
+			logger.Error("contest API rejected oauth token", zap.Int("status", apiErr.StatusCode), zap.Error(err))
+		case ApiErrorRateLimit:
+			logger.Warn("contest API rate limit exceeded", zap.Duration("retry_after", apiErr.RetryAfter), zap.Error(err))
+		}
+		if !apiErr.Kind.Retryable() || attempt >= c.MaxAttempts {
+			return res, err
+		}
+		delay := c.backoff(attempt)
+		if apiErr.RetryAfter > c.MaxBackoff {
+			return res, fmt.Errorf("server asked to retry after %v, which exceeds max backoff: %w", apiErr.RetryAfter, err)
+		}
+		if apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		logger.Info("retrying contest API request",
+			zap.Int("attempt", attempt),
+			zap.Stringer("kind", apiErr.Kind),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, fmt.Errorf("waiting for retry: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
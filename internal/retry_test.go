@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alex65536/yacontable/internal/fakecontest"
+	"golang.org/x/oauth2"
+)
+
+func TestApiRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []fakecontest.Response
+		maxBackoff   time.Duration
+		wantErr      bool
+		wantKind     ApiErrorKind
+		wantRequests int
+		minDuration  time.Duration
+	}{
+		{
+			name:         "temporary errors then success",
+			responses:    []fakecontest.Response{fakecontest.ServerError(http.StatusServiceUnavailable), fakecontest.ServerError(http.StatusBadGateway)},
+			wantRequests: 3,
+		},
+		{
+			name: "temporary errors exhaust attempts",
+			responses: []fakecontest.Response{
+				fakecontest.ServerError(http.StatusInternalServerError),
+				fakecontest.ServerError(http.StatusInternalServerError),
+				fakecontest.ServerError(http.StatusInternalServerError),
+				fakecontest.ServerError(http.StatusInternalServerError),
+			},
+			wantErr:      true,
+			wantKind:     ApiErrorTemporary,
+			wantRequests: 4,
+		},
+		{
+			name:         "malformed body is retried",
+			responses:    []fakecontest.Response{fakecontest.Malformed()},
+			wantRequests: 2,
+		},
+		{
+			name:         "unauthorized is not retried",
+			responses:    []fakecontest.Response{fakecontest.Unauthorized()},
+			wantErr:      true,
+			wantKind:     ApiErrorAuth,
+			wantRequests: 1,
+		},
+		{
+			name:         "not found is permanent",
+			responses:    []fakecontest.Response{fakecontest.ServerError(http.StatusNotFound)},
+			wantErr:      true,
+			wantKind:     ApiErrorPermanent,
+			wantRequests: 1,
+		},
+		{
+			name:         "rate limit honors retry-after",
+			responses:    []fakecontest.Response{fakecontest.RateLimited(1)},
+			maxBackoff:   2 * time.Second,
+			wantRequests: 2,
+			minDuration:  time.Second,
+		},
+		{
+			name:         "rate limit with retry-after above max backoff",
+			responses:    []fakecontest.Response{fakecontest.RateLimited(30)},
+			wantErr:      true,
+			wantKind:     ApiErrorRateLimit,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, func(conf *Config) {
+				if tt.maxBackoff != 0 {
+					conf.Retry.MaxBackoff = tt.maxBackoff
+				}
+			})
+			env.fake.EnqueueResponses(1, tt.responses...)
+			start := time.Now()
+			st, err := env.api.FetchStandings(context.Background(), Contest{Type: ContestTypeYandex, ID: 1})
+			elapsed := time.Since(start)
+			if n := env.fake.Requests(); n != tt.wantRequests {
+				t.Errorf("got %v requests, want %v", n, tt.wantRequests)
+			}
+			if elapsed < tt.minDuration {
+				t.Errorf("retried after %v, want at least %v", elapsed, tt.minDuration)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(st.Participants) != 4 {
+					t.Errorf("got %v participants, want 4", len(st.Participants))
+				}
+				return
+			}
+			var apiErr *ApiError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got error %v, want ApiError", err)
+			}
+			if apiErr.Kind != tt.wantKind {
+				t.Errorf("got error kind %v, want %v", apiErr.Kind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	conf, err := ParseConfig([]byte(`{"contests": [{"id": 1}], "retry": {"base_backoff": 1000, "max_backoff": 5000, "jitter": 0}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempt, want := range []time.Duration{1000, 2000, 4000, 5000, 5000} {
+		if got := conf.Retry.backoff(attempt + 1); got != want {
+			t.Errorf("backoff(%v) = %v, want %v", attempt+1, got, want)
+		}
+	}
+
+	jitter := 0.5
+	conf.Retry.Jitter = &jitter
+	for i := 0; i < 100; i++ {
+		if got := conf.Retry.backoff(2); got < 1000 || got > 3000 {
+			t.Fatalf("backoff(2) = %v with jitter 0.5, want between 1000 and 3000", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		val  string
+		want time.Duration
+	}{
+		{val: "", want: 0},
+		{val: "5", want: 5 * time.Second},
+		{val: "-1", want: 0},
+		{val: "soon", want: 0},
+		{val: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.val); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyTransportError(t *testing.T) {
+	retrieveErr := func(status int, code string) error {
+		return &oauth2.RetrieveError{
+			Response:  &http.Response{StatusCode: status, Header: http.Header{}},
+			ErrorCode: code,
+		}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want ApiErrorKind
+	}{
+		{name: "network error", err: errors.New("connection refused"), want: ApiErrorTemporary},
+		{name: "canceled", err: context.Canceled, want: ApiErrorPermanent},
+		{name: "not authorized", err: ErrNotAuthorized, want: ApiErrorAuth},
+		{name: "revoked token", err: retrieveErr(http.StatusBadRequest, "invalid_grant"), want: ApiErrorAuth},
+		{name: "token endpoint unavailable", err: retrieveErr(http.StatusServiceUnavailable, ""), want: ApiErrorTemporary},
+		{name: "token endpoint rate limit", err: retrieveErr(http.StatusTooManyRequests, ""), want: ApiErrorRateLimit},
+	}
+	for _, tt := range tests {
+		if got := classifyTransportError(tt.err).Kind; got != tt.want {
+			t.Errorf("%v: got kind %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ContestTypeYandex = "yandex"
+	ContestTypeURL    = "url"
+	ContestTypeFile   = "file"
+)
+
+type StandingsSource interface {
+	FetchStandings(ctx context.Context, contest Contest) (*Standings, error)
+}
+
+func NewStandingsSource(logger *zap.Logger, store *ConfigStore, ct Contest, api *Api) (StandingsSource, error) {
+	if err := validateContest(ct); err != nil {
+		return nil, err
+	}
+	switch ct.Type {
+	case ContestTypeYandex:
+		if api == nil {
+			return nil, fmt.Errorf("yandex contest api is not configured")
+		}
+		return api, nil
+	case ContestTypeURL:
+		return &urlSource{
+			client: &http.Client{},
+			store:  store,
+			logger: logger,
+		}, nil
+	default:
+		return &fileSource{}, nil
+	}
+}
+
+type urlSource struct {
+	client *http.Client
+	store  *ConfigStore
+	logger *zap.Logger
+}
+
+func (s *urlSource) fetch(ctx context.Context, conf *Config, contest Contest) (*Standings, error) {
+	ctx, cancel := context.WithTimeout(ctx, conf.RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, contest.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return nil, classifyTransportError(fmt.Errorf("sending request: %w", err))
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, rsp.Body)
+		_ = rsp.Body.Close()
+	}()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, classifyStatus(rsp, fmt.Errorf("got non-ok status: %v %v", rsp.StatusCode, rsp.Status))
+	}
+	d := json.NewDecoder(rsp.Body)
+	var st Standings
+	err = d.Decode(&st)
+	if err != nil {
+		return nil, classifyBodyError(fmt.Errorf("decoding json standings: %w", err))
+	}
+	return &st, nil
+}
+
+func (s *urlSource) FetchStandings(ctx context.Context, contest Contest) (*Standings, error) {
+	conf := s.store.Get()
+	st, err := withRetry(ctx, s.logger, &conf.Retry, func(ctx context.Context) (*Standings, error) {
+		return s.fetch(ctx, conf, contest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	st.Tag = contest.Tag
+	err = st.ValidateAndFix()
+	if err != nil {
+		return nil, fmt.Errorf("validating standings: %w", err)
+	}
+	return st, nil
+}
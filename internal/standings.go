@@ -4,37 +4,94 @@ import (
 	"fmt"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/alex65536/yacontable/pkg/goutil"
 	"go.uber.org/zap"
 )
 
+type RankingMode string
+
+const (
+	RankingModeScore RankingMode = "score"
+	RankingModeICPC  RankingMode = "icpc"
+)
+
 type ParticipantCell struct {
-	Score float64 `json:"score"`
+	Score    float64 `json:"score"`
+	Accepted bool    `json:"accepted,omitempty"`
+	Attempts int     `json:"attempts,omitempty"`
+	Time     int     `json:"time,omitempty"`
+}
+
+func (c ParticipantCell) Verdict() string {
+	switch {
+	case c.Accepted && c.Attempts <= 1:
+		return "+"
+	case c.Accepted:
+		return fmt.Sprintf("+%v", c.Attempts-1)
+	case c.Attempts > 0:
+		return fmt.Sprintf("-%v", c.Attempts)
+	default:
+		return ""
+	}
+}
+
+func (c ParticipantCell) FormatTime() string {
+	if !c.Accepted {
+		return ""
+	}
+	return fmt.Sprintf("%d:%02d", c.Time/60, c.Time%60)
 }
 
 type TaskHeader struct {
-	Name  string `json:"name"`
-	Title string `json:"title"`
+	Name     string   `json:"name"`
+	Title    string   `json:"title"`
+	Contest  int      `json:"contest"`
+	MaxScore *float64 `json:"max_score,omitempty"`
 }
 
 type Header struct {
 	Tasks []TaskHeader `json:"tasks"`
 }
 
+type Place struct {
+	Lo int `json:"lo"`
+	Hi int `json:"hi"`
+}
+
+func (p Place) String() string {
+	if p.Lo == p.Hi {
+		return strconv.Itoa(p.Lo)
+	}
+	return fmt.Sprintf("%v–%v", p.Lo, p.Hi)
+}
+
 type Participant struct {
-	Login  string            `json:"login"`
-	Name   string            `json:"name"`
-	TeamID int               `json:"team_id"`
-	Tasks  []ParticipantCell `json:"tasks"`
-	Total  float64           `json:"total"`
+	Login    string            `json:"login"`
+	Name     string            `json:"name"`
+	TeamID   int               `json:"team_id"`
+	Tasks    []ParticipantCell `json:"tasks"`
+	Total    float64           `json:"total"`
+	RawTotal float64           `json:"raw_total"`
+	Solved   int               `json:"solved"`
+	Penalty  int               `json:"penalty"`
+	Days     []DayResult       `json:"days,omitempty"`
+	Place    Place             `json:"place"`
 }
 
 type Standings struct {
-	Tag          string        `json:"tag"`
-	Header       Header        `json:"header"`
-	Participants []Participant `json:"participants"`
+	Tag               string        `json:"tag"`
+	Ranking           RankingMode   `json:"ranking,omitempty"`
+	PenaltyPerAttempt int           `json:"penalty_per_attempt,omitempty"`
+	Aggregation       *Aggregation  `json:"aggregation,omitempty"`
+	Header            Header        `json:"header"`
+	Participants      []Participant `json:"participants"`
+}
+
+func (s *Standings) IsICPC() bool {
+	return s.Ranking == RankingModeICPC
 }
 
 func (s *Standings) ValidateAndFix() error {
@@ -44,23 +101,89 @@ func (s *Standings) ValidateAndFix() error {
 			return fmt.Errorf("participant %v:%v has %v tasks, but header has %v", i, p.Login, len(p.Tasks), len(s.Header.Tasks))
 		}
 		total := 0.0
+		solved := 0
+		penalty := 0
 		for _, v := range p.Tasks {
 			total += v.Score
+			if v.Accepted {
+				solved++
+				penalty += v.Time + s.PenaltyPerAttempt*max(v.Attempts-1, 0)
+			}
 		}
 		p.Total = total
+		p.RawTotal = total
+		p.Solved = solved
+		p.Penalty = penalty
+	}
+	if s.Aggregation != nil {
+		if err := s.Aggregation.apply(s); err != nil {
+			return fmt.Errorf("aggregating: %w", err)
+		}
 	}
 	s.sort()
+	s.ComputePlaces()
 	return nil
 }
 
-func (s *Standings) sort() {
-	slices.SortFunc(s.Participants, func(a, b Participant) int {
-		if a.Total > b.Total {
+func (s *Standings) HasMaxScores() bool {
+	for _, h := range s.Header.Tasks {
+		if h.MaxScore != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Standings) MaxTotal() (float64, bool) {
+	if s.Aggregation != nil {
+		return s.Aggregation.maxTotal(s)
+	}
+	total := 0.0
+	for _, h := range s.Header.Tasks {
+		if h.MaxScore == nil {
+			return 0.0, false
+		}
+		total += *h.MaxScore
+	}
+	return total, true
+}
+
+func (s *Standings) SetRanking(mode RankingMode, penaltyPerAttempt int) error {
+	s.Ranking = mode
+	s.PenaltyPerAttempt = penaltyPerAttempt
+	return s.ValidateAndFix()
+}
+
+func (s *Standings) compareResults(a, b *Participant) int {
+	if s.IsICPC() {
+		if a.Solved != b.Solved {
+			if a.Solved > b.Solved {
+				return -1
+			}
+			return 1
+		}
+		if a.Penalty < b.Penalty {
 			return -1
 		}
-		if a.Total < b.Total {
+		if a.Penalty > b.Penalty {
 			return 1
 		}
+		return 0
+	}
+	if a.Total > b.Total {
+		return -1
+	}
+	if a.Total < b.Total {
+		return 1
+	}
+	return 0
+}
+
+func (s *Standings) sort() {
+	slices.SortFunc(s.Participants, func(a, b Participant) int {
+		if c := s.compareResults(&a, &b); c != 0 {
+			return c
+		}
 		if a.Login < b.Login {
 			return -1
 		}
@@ -71,6 +194,19 @@ func (s *Standings) sort() {
 	})
 }
 
+func (s *Standings) ComputePlaces() {
+	for lo := 0; lo < len(s.Participants); {
+		hi := lo + 1
+		for hi < len(s.Participants) && s.compareResults(&s.Participants[lo], &s.Participants[hi]) == 0 {
+			hi++
+		}
+		for i := lo; i < hi; i++ {
+			s.Participants[i].Place = Place{Lo: lo + 1, Hi: hi}
+		}
+		lo = hi
+	}
+}
+
 type FilterMode int
 
 const (
@@ -91,16 +227,12 @@ func makeFilter(src func(p Participant) bool, mode FilterMode) func(p Participan
 	}
 }
 
-func (s *Standings) FilterRegex(loginRegex string, mode FilterMode) (*Standings, error) {
-	re, err := regexp.Compile(loginRegex)
-	if err != nil {
-		return nil, fmt.Errorf("compiling regex: %w", err)
-	}
+func (s *Standings) FilterRegex(loginRegex *regexp.Regexp, mode FilterMode) *Standings {
 	res := *s
 	res.Participants = goutil.FilterCopy(s.Participants, makeFilter(func(p Participant) bool {
-		return re.MatchString(p.Login)
+		return loginRegex.MatchString(p.Login)
 	}, mode))
-	return &res, nil
+	return &res
 }
 
 func (s *Standings) FilterPrefix(loginPrefix string, mode FilterMode) *Standings {
@@ -127,6 +259,9 @@ func MergeStandings(logger *zap.Logger, sts ...*Standings) (*Standings, error) {
 
 	participants := make(map[string]*pinfo)
 	for _, s := range sts {
+		if s == nil {
+			continue
+		}
 		for _, p := range s.Participants {
 			if val, ok := participants[p.Login]; ok {
 				if val.p.Name != p.Name {
@@ -148,12 +283,24 @@ func MergeStandings(logger *zap.Logger, sts ...*Standings) (*Standings, error) {
 	}
 
 	res := Standings{}
+	rankingSet := false
 	for i, s := range sts {
+		if s == nil {
+			continue
+		}
+		if !rankingSet {
+			res.Ranking = s.Ranking
+			res.PenaltyPerAttempt = s.PenaltyPerAttempt
+			rankingSet = true
+		} else if res.Ranking != s.Ranking || res.PenaltyPerAttempt != s.PenaltyPerAttempt {
+			logger.Warn("ranking mode mismatch between standings", zap.Int("standings_id", i), zap.String("ranking", string(s.Ranking)))
+		}
 		for _, p := range participants {
 			p.used = false
 		}
 		for _, t := range s.Header.Tasks {
 			tt := t
+			tt.Contest = i
 			if s.Tag != "" {
 				tt.Title = fmt.Sprintf("%v-%v", s.Tag, tt.Title)
 			}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestComputePlaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranking RankingMode
+		results []Participant
+		want    []Place
+	}{
+		{
+			name:    "no ties",
+			ranking: RankingModeScore,
+			results: []Participant{{Total: 30}, {Total: 20}, {Total: 10}},
+			want:    []Place{{1, 1}, {2, 2}, {3, 3}},
+		},
+		{
+			name:    "tie in the middle",
+			ranking: RankingModeScore,
+			results: []Participant{{Total: 30}, {Total: 20}, {Total: 20}, {Total: 10}},
+			want:    []Place{{1, 1}, {2, 3}, {2, 3}, {4, 4}},
+		},
+		{
+			name:    "everyone tied",
+			ranking: RankingModeScore,
+			results: []Participant{{Total: 0}, {Total: 0}, {Total: 0}},
+			want:    []Place{{1, 3}, {1, 3}, {1, 3}},
+		},
+		{
+			name:    "ties at both ends",
+			ranking: RankingModeScore,
+			results: []Participant{{Total: 5}, {Total: 5}, {Total: 3}, {Total: 1}, {Total: 1}},
+			want:    []Place{{1, 2}, {1, 2}, {3, 3}, {4, 5}, {4, 5}},
+		},
+		{
+			name:    "score ranking ignores penalty",
+			ranking: RankingModeScore,
+			results: []Participant{{Total: 10, Penalty: 5}, {Total: 10, Penalty: 50}},
+			want:    []Place{{1, 2}, {1, 2}},
+		},
+		{
+			name:    "icpc penalty breaks ties",
+			ranking: RankingModeICPC,
+			results: []Participant{{Solved: 3, Penalty: 100}, {Solved: 3, Penalty: 120}, {Solved: 2, Penalty: 10}},
+			want:    []Place{{1, 1}, {2, 2}, {3, 3}},
+		},
+		{
+			name:    "icpc ignores score",
+			ranking: RankingModeICPC,
+			results: []Participant{{Solved: 2, Penalty: 60, Total: 200}, {Solved: 2, Penalty: 60, Total: 100}, {Solved: 1, Penalty: 60}},
+			want:    []Place{{1, 2}, {1, 2}, {3, 3}},
+		},
+		{
+			name:    "empty",
+			ranking: RankingModeScore,
+		},
+	}
+	for _, tt := range tests {
+		s := &Standings{Ranking: tt.ranking, Participants: tt.results}
+		s.ComputePlaces()
+		var got []Place
+		for _, p := range s.Participants {
+			got = append(got, p.Place)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: got places %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceString(t *testing.T) {
+	if got := (Place{Lo: 3, Hi: 3}).String(); got != "3" {
+		t.Errorf("got %q, want %q", got, "3")
+	}
+	if got := (Place{Lo: 2, Hi: 4}).String(); got != "2–4" {
+		t.Errorf("got %q, want %q", got, "2–4")
+	}
+}
@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type TeamScoreMode string
+
+const (
+	TeamScoreModeSum   TeamScoreMode = "sum"
+	TeamScoreModeAvg   TeamScoreMode = "avg"
+	TeamScoreModeBestN TeamScoreMode = "best_n"
+)
+
+type TeamRanking struct {
+	Mode  TeamScoreMode `json:"mode"`
+	BestN int           `json:"best_n,omitempty"`
+}
+
+func (r TeamRanking) Validate() error {
+	switch r.Mode {
+	case TeamScoreModeSum, TeamScoreModeAvg:
+		return nil
+	case TeamScoreModeBestN:
+		if r.BestN <= 0 {
+			return fmt.Errorf("best_n must be positive, got %v", r.BestN)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown team score mode %q", r.Mode)
+	}
+}
+
+func (r TeamRanking) String() string {
+	switch r.Mode {
+	case TeamScoreModeAvg:
+		return "average of members"
+	case TeamScoreModeBestN:
+		return fmt.Sprintf("sum of the best %v members", r.BestN)
+	default:
+		return "sum of members"
+	}
+}
+
+type TeamResult struct {
+	TeamID  int           `json:"team_id"`
+	Name    string        `json:"name"`
+	Members []Participant `json:"-"`
+	Counted []bool        `json:"-"`
+	Total   float64       `json:"total"`
+	Solved  float64       `json:"solved"`
+	Penalty float64       `json:"penalty"`
+	Place   Place         `json:"place"`
+}
+
+func (t *TeamResult) NumMembers() int {
+	return len(t.Members)
+}
+
+func (t *TeamResult) NumCounted() int {
+	res := 0
+	for _, c := range t.Counted {
+		if c {
+			res++
+		}
+	}
+	return res
+}
+
+type TeamStandings struct {
+	Ranking TeamRanking
+	ICPC    bool
+	Teams   []TeamResult
+}
+
+func (s *TeamStandings) compareResults(a, b *TeamResult) int {
+	if s.ICPC {
+		if a.Solved != b.Solved {
+			if a.Solved > b.Solved {
+				return -1
+			}
+			return 1
+		}
+		if a.Penalty < b.Penalty {
+			return -1
+		}
+		if a.Penalty > b.Penalty {
+			return 1
+		}
+		return 0
+	}
+	if a.Total > b.Total {
+		return -1
+	}
+	if a.Total < b.Total {
+		return 1
+	}
+	return 0
+}
+
+func (s *TeamStandings) sort() {
+	slices.SortFunc(s.Teams, func(a, b TeamResult) int {
+		if c := s.compareResults(&a, &b); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+func (s *TeamStandings) computePlaces() {
+	for lo := 0; lo < len(s.Teams); {
+		hi := lo + 1
+		for hi < len(s.Teams) && s.compareResults(&s.Teams[lo], &s.Teams[hi]) == 0 {
+			hi++
+		}
+		for i := lo; i < hi; i++ {
+			s.Teams[i].Place = Place{Lo: lo + 1, Hi: hi}
+		}
+		lo = hi
+	}
+}
+
+func (r TeamRanking) apply(t *TeamResult) {
+	// Members are already sorted by their results, so the best members go first.
+	t.Counted = make([]bool, len(t.Members))
+	n := len(t.Members)
+	if r.Mode == TeamScoreModeBestN {
+		n = min(n, r.BestN)
+	}
+	for i := 0; i < n; i++ {
+		p := &t.Members[i]
+		t.Counted[i] = true
+		t.Total += p.Total
+		t.Solved += float64(p.Solved)
+		t.Penalty += float64(p.Penalty)
+	}
+	if r.Mode == TeamScoreModeAvg && n != 0 {
+		t.Total /= float64(n)
+		t.Solved /= float64(n)
+		t.Penalty /= float64(n)
+	}
+}
+
+func BuildTeamStandings(st *Standings, teams []TeamConfig, r TeamRanking) (*TeamStandings, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	res := &TeamStandings{
+		Ranking: r,
+		ICPC:    st.IsICPC(),
+		Teams:   make([]TeamResult, len(teams)),
+	}
+	for i, t := range teams {
+		res.Teams[i] = TeamResult{
+			TeamID: i,
+			Name:   t.Name,
+		}
+	}
+	for _, p := range st.Participants {
+		if p.TeamID < 0 || p.TeamID >= len(teams) {
+			continue
+		}
+		t := &res.Teams[p.TeamID]
+		t.Members = append(t.Members, p)
+	}
+	for i := range res.Teams {
+		r.apply(&res.Teams[i])
+	}
+	res.sort()
+	res.computePlaces()
+	return res, nil
+}
+
+type teamFilter struct {
+	ranking TeamRanking
+	expand  int
+}
+
+func parseTeamFilter(req *http.Request, conf *Config) teamFilter {
+	query := req.URL.Query()
+	res := teamFilter{
+		ranking: conf.TeamRanking,
+		expand:  -1,
+	}
+	if mode := TeamScoreMode(query.Get("mode")); mode != "" {
+		res.ranking.Mode = mode
+	}
+	if nStr := query.Get("n"); nStr != "" {
+		if n, err := strconv.ParseInt(nStr, 10, 0); err == nil {
+			res.ranking.BestN = int(n)
+		}
+	}
+	if expandStr := query.Get("expand"); expandStr == "all" {
+		res.expand = len(conf.Teams)
+	} else if expandStr != "" {
+		if expand, err := strconv.ParseInt(expandStr, 10, 0); err == nil && 0 <= int(expand) && int(expand) < len(conf.Teams) {
+			res.expand = int(expand)
+		}
+	}
+	return res
+}
+
+func (p *Presenter) doBuildTeams(req *http.Request) ([]byte, error) {
+	type state struct {
+		Conf      *Config
+		Ranking   TeamRanking
+		Modes     []TeamScoreMode
+		Expand    int
+		ExpandAll bool
+		Teams     *TeamStandings
+		Standings *Standings
+		Snapshot  *Snapshot
+	}
+
+	snap, err := p.getSnapshot(isJury(req))
+	if err != nil {
+		return nil, fmt.Errorf("getting statements: %w", err)
+	}
+	conf := p.renderConf(snap)
+	f := parseTeamFilter(req, conf)
+	ts, err := BuildTeamStandings(snap.Standings, conf.Teams, f.ranking)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errBadTeamRanking, err)
+	}
+	var b bytes.Buffer
+	err = p.t.ExecuteTemplate(&b, "teams.html", &state{
+		Conf:      conf,
+		Ranking:   f.ranking,
+		Modes:     []TeamScoreMode{TeamScoreModeSum, TeamScoreModeAvg, TeamScoreModeBestN},
+		Expand:    f.expand,
+		ExpandAll: f.expand == len(conf.Teams),
+		Teams:     ts,
+		Standings: snap.Standings,
+		Snapshot:  snap,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+var errBadTeamRanking = errors.New("bad team ranking")
+
+func (p *Presenter) ServeTeams(w http.ResponseWriter, req *http.Request) {
+	p.logger.Info("get teams", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "use GET method")
+		return
+	}
+	if !p.conf().DisplayTeams {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "team standings are disabled")
+		return
+	}
+	b, err := p.doBuildTeams(req)
+	if errors.Is(err, errBadTeamRanking) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, err.Error())
+		return
+	}
+	if errors.Is(err, ErrStandingsNotLoaded) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "standings are not loaded yet, please try again later")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		p.logger.Error("error serving request", zap.Error(err))
+		_, _ = io.WriteString(w, "got error: "+err.Error())
+		return
+	}
+	_, _ = w.Write(b)
+}
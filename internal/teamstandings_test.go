@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestBuildTeamStandings(t *testing.T) {
+	teams := []TeamConfig{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}}
+	// The participants are sorted by their results, as in the standings.
+	st := &Standings{
+		Participants: []Participant{
+			{Login: "a1", TeamID: 0, Total: 100, Solved: 3, Penalty: 100},
+			{Login: "b1", TeamID: 1, Total: 90, Solved: 3, Penalty: 50},
+			{Login: "b2", TeamID: 1, Total: 60, Solved: 1, Penalty: 10},
+			{Login: "x", TeamID: -1, Total: 50, Solved: 1, Penalty: 5},
+			{Login: "b3", TeamID: 1, Total: 30},
+			{Login: "a2", TeamID: 0, Total: 10, Solved: 1, Penalty: 20},
+		},
+	}
+	type row struct {
+		name    string
+		total   float64
+		place   Place
+		counted string
+	}
+	tests := []struct {
+		name    string
+		ranking RankingMode
+		r       TeamRanking
+		want    []row
+	}{
+		{
+			name: "sum",
+			r:    TeamRanking{Mode: TeamScoreModeSum},
+			want: []row{{"B", 180, Place{1, 1}, "[true true true]"}, {"A", 110, Place{2, 2}, "[true true]"}, {"C", 0, Place{3, 4}, "[]"}, {"D", 0, Place{3, 4}, "[]"}},
+		},
+		{
+			name: "avg",
+			r:    TeamRanking{Mode: TeamScoreModeAvg},
+			want: []row{{"B", 60, Place{1, 1}, "[true true true]"}, {"A", 55, Place{2, 2}, "[true true]"}, {"C", 0, Place{3, 4}, "[]"}, {"D", 0, Place{3, 4}, "[]"}},
+		},
+		{
+			name: "best 1",
+			r:    TeamRanking{Mode: TeamScoreModeBestN, BestN: 1},
+			want: []row{{"A", 100, Place{1, 1}, "[true false]"}, {"B", 90, Place{2, 2}, "[true false false]"}, {"C", 0, Place{3, 4}, "[]"}, {"D", 0, Place{3, 4}, "[]"}},
+		},
+		{
+			name: "best 2",
+			r:    TeamRanking{Mode: TeamScoreModeBestN, BestN: 2},
+			want: []row{{"B", 150, Place{1, 1}, "[true true false]"}, {"A", 110, Place{2, 2}, "[true true]"}, {"C", 0, Place{3, 4}, "[]"}, {"D", 0, Place{3, 4}, "[]"}},
+		},
+		{
+			// Both teams solved 4 problems, B has less penalty.
+			name:    "icpc",
+			ranking: RankingModeICPC,
+			r:       TeamRanking{Mode: TeamScoreModeSum},
+			want:    []row{{"B", 180, Place{1, 1}, "[true true true]"}, {"A", 110, Place{2, 2}, "[true true]"}, {"C", 0, Place{3, 4}, "[]"}, {"D", 0, Place{3, 4}, "[]"}},
+		},
+		{
+			// The best members of both teams solved 3 problems, the one from B has less penalty.
+			name:    "icpc best 1",
+			ranking: RankingModeICPC,
+			r:       TeamRanking{Mode: TeamScoreModeBestN, BestN: 1},
+			want:    []row{{"B", 90, Place{1, 1}, "[true false false]"}, {"A", 100, Place{2, 2}, "[true false]"}, {"C", 0, Place{3, 4}, "[]"}, {"D", 0, Place{3, 4}, "[]"}},
+		},
+	}
+	for _, tt := range tests {
+		st.Ranking = tt.ranking
+		ts, err := BuildTeamStandings(st, teams, tt.r)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.name, err)
+			continue
+		}
+		var got []row
+		for _, team := range ts.Teams {
+			got = append(got, row{team.Name, team.Total, team.Place, fmt.Sprint(team.Counted)})
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: got teams %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := BuildTeamStandings(st, teams, TeamRanking{Mode: TeamScoreModeBestN}); err == nil {
+		t.Errorf("no error for best_n without n")
+	}
+}
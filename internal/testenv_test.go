@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alex65536/yacontable/internal/fakecontest"
+	"go.uber.org/zap"
+)
+
+type testEnv struct {
+	fake   *fakecontest.Server
+	store  *ConfigStore
+	api    *Api
+	keeper *Keeper
+}
+
+// newTestEnv starts the fake contest API with the fixtures from fakecontest/testdata and creates a keeper for
+// contests 1 and 2 on top of it. The working directory is switched to a temporary one, so the state written
+// by the keeper does not leak between tests.
+func newTestEnv(t *testing.T, mod func(conf *Config)) *testEnv {
+	t.Helper()
+	fake := fakecontest.NewServer()
+	if err := fake.LoadFixtures(filepath.Join(packageDir, "fakecontest", "testdata")); err != nil {
+		t.Fatalf("loading fixtures: %v", err)
+	}
+	srv := fake.Start()
+	t.Cleanup(srv.Close)
+
+	conf := &Config{
+		ApiBaseURL: srv.URL,
+		ApiNoAuth:  true,
+		Contests: []Contest{
+			{ID: 1, Tag: "D1"},
+			{ID: 2, Tag: "D2"},
+		},
+		Retry: RetryConfig{
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+		},
+	}
+	if mod != nil {
+		mod(conf)
+	}
+	conf.FillDefaults()
+	if err := conf.Validate(); err != nil {
+		t.Fatalf("invalid test config: %v", err)
+	}
+
+	chdir(t, t.TempDir())
+	logger := zap.NewNop()
+	store := NewConfigStore(conf)
+	api, err := NewApi(logger, context.Background(), store, &StaticSecrets{})
+	if err != nil {
+		t.Fatalf("creating api: %v", err)
+	}
+	keeper, err := NewKeeper(logger, store, api)
+	if err != nil {
+		t.Fatalf("creating keeper: %v", err)
+	}
+	return &testEnv{
+		fake:   fake,
+		store:  store,
+		api:    api,
+		keeper: keeper,
+	}
+}
+
+func (e *testEnv) refresh() {
+	e.keeper.refresh(context.Background(), true)
+}
+
+func (e *testEnv) presenter(t *testing.T) *Presenter {
+	t.Helper()
+	// The templates are loaded relative to the repository root.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Dir(packageDir)); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPresenter(zap.NewNop(), e.keeper, e.api.Authorizer(), e.store)
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	if err != nil {
+		t.Fatalf("creating presenter: %v", err)
+	}
+	return p
+}
+
+// packageDir is the directory of the package sources, captured before any test changes the working directory.
+var packageDir = func() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	return wd
+}()
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+}
+
+func fakeStandings(logins ...string) fakecontest.Standings {
+	st := fakecontest.Standings{
+		Titles: []fakecontest.Title{{Name: "A", Title: "A"}},
+	}
+	for i, login := range logins {
+		st.Rows = append(st.Rows, fakecontest.Row{
+			ParticipantInfo: fakecontest.ParticipantInfo{Login: login},
+			ProblemResults:  []fakecontest.ProblemResult{{Score: strconv.Itoa(len(logins) - i)}},
+		})
+	}
+	return st
+}
+
+func fakePage(st fakecontest.Standings) fakecontest.Response {
+	data, err := json.Marshal(&st)
+	if err != nil {
+		panic(err)
+	}
+	return fakecontest.Response{Status: http.StatusOK, Body: string(data)}
+}
+
+func participantLogins(st *Standings) []string {
+	res := make([]string, len(st.Participants))
+	for i, p := range st.Participants {
+		res[i] = p.Login
+	}
+	return res
+}
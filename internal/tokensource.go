@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+var ErrNotAuthorized = errors.New("not authorized in contest API")
+
+type persistingTokenSource struct {
+	logger    *zap.Logger
+	ctx       context.Context
+	oauthConf *oauth2.Config
+
+	mu   sync.Mutex
+	base oauth2.TokenSource
+	last *oauth2.Token
+}
+
+func newPersistingTokenSource(logger *zap.Logger, ctx context.Context, oauthConf *oauth2.Config, tok *oauth2.Token) *persistingTokenSource {
+	s := &persistingTokenSource{
+		logger:    logger,
+		ctx:       ctx,
+		oauthConf: oauthConf,
+	}
+	if tok != nil {
+		s.setTokenUnlocked(tok)
+	}
+	return s
+}
+
+func (s *persistingTokenSource) setTokenUnlocked(tok *oauth2.Token) {
+	s.last = tok
+	s.base = s.oauthConf.TokenSource(s.ctx, tok)
+}
+
+// isTokenRevoked reports whether the token endpoint rejected the refresh token itself. Transient
+// failures of the endpoint (like 5xx or 429) are returned as RetrieveError too, but the token is still valid.
+func isTokenRevoked(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	if retrieveErr.ErrorCode == "invalid_grant" {
+		return true
+	}
+	if retrieveErr.Response == nil {
+		return false
+	}
+	code := retrieveErr.Response.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests && retrieveErr.ErrorCode != ""
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.base == nil {
+		return nil, ErrNotAuthorized
+	}
+	tok, err := s.base.Token()
+	if err != nil {
+		if isTokenRevoked(err) {
+			s.logger.Warn("cannot refresh oauth2 token, re-authorization is required", zap.Error(err))
+			s.base = nil
+		}
+		return nil, err
+	}
+	if tok.AccessToken != s.last.AccessToken {
+		s.logger.Info("oauth2 token was refreshed", zap.Time("expiry", tok.Expiry))
+		newTok := *tok
+		newTok.TokenType = "OAuth" // HACK
+		if err := StoreDynamicSecrets(&DynamicSecrets{Token: &newTok}); err != nil {
+			s.logger.Error("cannot store refreshed token", zap.Error(err))
+		}
+		s.last = &newTok
+	}
+	res := *s.last
+	return &res, nil
+}
+
+func (s *persistingTokenSource) SetToken(tok *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := StoreDynamicSecrets(&DynamicSecrets{Token: tok}); err != nil {
+		return err
+	}
+	s.setTokenUnlocked(tok)
+	return nil
+}
+
+func (s *persistingTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.base == nil {
+		return
+	}
+	s.logger.Warn("oauth2 token was rejected by contest API, re-authorization is required")
+	s.base = nil
+}
+
+func (s *persistingTokenSource) Status() (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.base == nil {
+		return false, time.Time{}
+	}
+	return true, s.last.Expiry
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestIsTokenRevoked(t *testing.T) {
+	retrieveErr := func(status int, code string) error {
+		return &oauth2.RetrieveError{
+			Response:  &http.Response{StatusCode: status},
+			ErrorCode: code,
+		}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "invalid grant", err: retrieveErr(http.StatusBadRequest, "invalid_grant"), want: true},
+		{name: "wrapped invalid grant", err: fmt.Errorf("refreshing: %w", retrieveErr(http.StatusBadRequest, "invalid_grant")), want: true},
+		{name: "invalid client", err: retrieveErr(http.StatusUnauthorized, "invalid_client"), want: true},
+		{name: "server error", err: retrieveErr(http.StatusInternalServerError, ""), want: false},
+		{name: "server error with code", err: retrieveErr(http.StatusServiceUnavailable, "temporarily_unavailable"), want: false},
+		{name: "rate limit", err: retrieveErr(http.StatusTooManyRequests, "slow_down"), want: false},
+		{name: "client error without code", err: retrieveErr(http.StatusBadRequest, ""), want: false},
+		{name: "not a retrieve error", err: errors.New("connection reset"), want: false},
+	}
+	for _, tt := range tests {
+		if got := isTokenRevoked(tt.err); got != tt.want {
+			t.Errorf("%v: isTokenRevoked() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+const xlsxMaxSheetNameLen = 31
+
+type xlsxBuilder struct {
+	p      *Presenter
+	conf   *Config
+	f      *excelize.File
+	styles map[string]int
+	names  map[string]struct{}
+}
+
+func (b *xlsxBuilder) sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > xlsxMaxSheetNameLen {
+		name = string(runes[:xlsxMaxSheetNameLen])
+	}
+	res := name
+	for i := 2; ; i++ {
+		if _, ok := b.names[strings.ToLower(res)]; !ok {
+			break
+		}
+		suffix := fmt.Sprintf(" (%v)", i)
+		runes := []rune(name)
+		if len(runes)+len(suffix) > xlsxMaxSheetNameLen {
+			runes = runes[:xlsxMaxSheetNameLen-len(suffix)]
+		}
+		res = string(runes) + suffix
+	}
+	b.names[strings.ToLower(res)] = struct{}{}
+	return res
+}
+
+func (b *xlsxBuilder) style(key string, s *excelize.Style) (int, error) {
+	if id, ok := b.styles[key]; ok {
+		return id, nil
+	}
+	id, err := b.f.NewStyle(s)
+	if err != nil {
+		return 0, err
+	}
+	b.styles[key] = id
+	return id, nil
+}
+
+func (b *xlsxBuilder) scoreStyle(score float64, maxScore *float64, bold bool) (int, error) {
+	font := &excelize.Font{Bold: bold}
+	if maxScore != nil && *maxScore > 0 {
+		font.Color = getScoreColor(score / *maxScore)
+	}
+	return b.style(fmt.Sprintf("score:%v:%v", font.Color, bold), &excelize.Style{Font: font})
+}
+
+func (b *xlsxBuilder) setCell(sheet string, col, row int, value any, style int) error {
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return err
+	}
+	if err := b.f.SetCellValue(sheet, cell, value); err != nil {
+		return err
+	}
+	if style != 0 {
+		if err := b.f.SetCellStyle(sheet, cell, cell, style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *xlsxBuilder) writeICPCResults(sheet string, row, firstTaskCol int, pp *Participant) error {
+	for j, t := range pp.Tasks {
+		color := ""
+		if t.Accepted {
+			color = "#008000"
+		} else if t.Attempts > 0 {
+			color = "#c00000"
+		}
+		style, err := b.style("icpc:"+color, &excelize.Style{Font: &excelize.Font{Bold: true, Color: color}})
+		if err != nil {
+			return fmt.Errorf("creating style: %w", err)
+		}
+		if err := b.setCell(sheet, firstTaskCol+j, row, formatExportCell(t), style); err != nil {
+			return fmt.Errorf("writing participant: %w", err)
+		}
+	}
+	if err := b.setCell(sheet, firstTaskCol+len(pp.Tasks), row, pp.Solved, 0); err != nil {
+		return fmt.Errorf("writing participant: %w", err)
+	}
+	if err := b.setCell(sheet, firstTaskCol+len(pp.Tasks)+1, row, pp.Penalty, 0); err != nil {
+		return fmt.Errorf("writing participant: %w", err)
+	}
+	return nil
+}
+
+func (b *xlsxBuilder) writeSheet(sheet string, st *Standings) error {
+	headStyle, err := b.style("head", &excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "#212144"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#d9d9f2"}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating style: %w", err)
+	}
+	fullStyle, err := b.style("full", &excelize.Style{
+		Font: &excelize.Font{Color: "#008000"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#d9d9f2"}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating style: %w", err)
+	}
+
+	header := exportHeader(b.conf, st)
+	firstTaskCol := exportInfoColumns(b.conf) + 1
+	for i, h := range header {
+		if err := b.setCell(sheet, i+1, 1, h, headStyle); err != nil {
+			return fmt.Errorf("writing header: %w", err)
+		}
+	}
+
+	for i := range st.Participants {
+		pp := &st.Participants[i]
+		row := i + 2
+		info := exportParticipant(b.conf, st, pp)[:firstTaskCol-1]
+		var place any = pp.Place.String()
+		if pp.Place.Lo == pp.Place.Hi {
+			place = pp.Place.Lo
+		}
+		if err := b.setCell(sheet, 1, row, place, 0); err != nil {
+			return fmt.Errorf("writing participant: %w", err)
+		}
+		for j, v := range info[1:] {
+			if err := b.setCell(sheet, j+2, row, v, 0); err != nil {
+				return fmt.Errorf("writing participant: %w", err)
+			}
+		}
+		if st.IsICPC() {
+			if err := b.writeICPCResults(sheet, row, firstTaskCol, pp); err != nil {
+				return err
+			}
+			continue
+		}
+		for j, t := range pp.Tasks {
+			style, err := b.scoreStyle(t.Score, st.Header.Tasks[j].MaxScore, false)
+			if err != nil {
+				return fmt.Errorf("creating style: %w", err)
+			}
+			if err := b.setCell(sheet, firstTaskCol+j, row, t.Score, style); err != nil {
+				return fmt.Errorf("writing participant: %w", err)
+			}
+		}
+		var maxTotalPtr *float64
+		if maxTotal, ok := st.MaxTotal(); ok {
+			maxTotalPtr = &maxTotal
+		}
+		style, err := b.scoreStyle(pp.Total, maxTotalPtr, true)
+		if err != nil {
+			return fmt.Errorf("creating style: %w", err)
+		}
+		if err := b.setCell(sheet, firstTaskCol+len(pp.Tasks), row, pp.Total, style); err != nil {
+			return fmt.Errorf("writing participant: %w", err)
+		}
+		if st.IsNormalized() {
+			if err := b.setCell(sheet, firstTaskCol+len(pp.Tasks)+1, row, pp.RawTotal, 0); err != nil {
+				return fmt.Errorf("writing participant: %w", err)
+			}
+		}
+	}
+
+	if fullScores := b.p.calcNumFullScores(st); fullScores != nil {
+		row := len(st.Participants) + 2
+		labelCol := min(2, firstTaskCol-1)
+		for col := 1; col < firstTaskCol; col++ {
+			var value any
+			if col == labelCol {
+				value = "Full solutions"
+			}
+			if err := b.setCell(sheet, col, row, value, fullStyle); err != nil {
+				return fmt.Errorf("writing footer: %w", err)
+			}
+		}
+		for j, cnt := range fullScores {
+			var value any
+			if cnt >= 0 {
+				value = cnt
+			}
+			if err := b.setCell(sheet, firstTaskCol+j, row, value, fullStyle); err != nil {
+				return fmt.Errorf("writing footer: %w", err)
+			}
+		}
+	}
+
+	err = b.f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return fmt.Errorf("freezing header: %w", err)
+	}
+	return nil
+}
+
+func (p *Presenter) buildXLSX(conf *Config, snap *Snapshot, flt filter) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	b := &xlsxBuilder{
+		p:      p,
+		conf:   conf,
+		f:      f,
+		styles: make(map[string]int),
+		names:  make(map[string]struct{}),
+	}
+
+	mainSheet := b.sheetName("Standings")
+	if err := f.SetSheetName(f.GetSheetName(0), mainSheet); err != nil {
+		return nil, fmt.Errorf("renaming sheet: %w", err)
+	}
+	if err := b.writeSheet(mainSheet, flt.apply(snap.Standings)); err != nil {
+		return nil, fmt.Errorf("writing merged standings: %w", err)
+	}
+
+	for _, c := range snap.Contests {
+		if c.Standings == nil {
+			continue
+		}
+		sheet := b.sheetName(c.Contest.String())
+		if _, err := f.NewSheet(sheet); err != nil {
+			return nil, fmt.Errorf("creating sheet: %w", err)
+		}
+		if err := b.writeSheet(sheet, flt.apply(c.Standings)); err != nil {
+			return nil, fmt.Errorf("writing %v: %w", c.Contest, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, fmt.Errorf("writing xlsx: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *Presenter) ServeXLSX(w http.ResponseWriter, req *http.Request) {
+	p.logger.Info("export xlsx", zap.String("uri", req.RequestURI), zap.String("addr", req.RemoteAddr), zap.String("user_agent", req.UserAgent()))
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "use GET method")
+		return
+	}
+	snap, ok := p.getSnapshotForExport(w, req)
+	if !ok {
+		return
+	}
+	conf := p.renderConf(snap)
+	b, err := p.buildXLSX(conf, snap, parseFilter(req, conf))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		p.logger.Error("error building xlsx", zap.Error(err))
+		_, _ = io.WriteString(w, "got error: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"standings.xlsx\"")
+	_, _ = w.Write(b)
+}
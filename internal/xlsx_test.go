@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestPresenterXLSX(t *testing.T) {
+	maxScore := 100.0
+	env := newTestEnv(t, func(conf *Config) {
+		conf.DisplayNames = true
+		conf.DisplayTeams = true
+		conf.MaxScorePerTask = &maxScore
+		conf.Teams = []TeamConfig{{Name: "Red", Logins: []string{"my-login-1", "my-login-4"}}}
+	})
+	p := env.presenter(t)
+	env.refresh()
+
+	w := serve(http.HandlerFunc(p.ServeXLSX), "/export.xlsx")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %v: %v", w.Code, w.Body.String())
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("opening xlsx: %v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.GetSheetList(), []string{"Standings", "D1", "D2"}; !slices.Equal(got, want) {
+		t.Errorf("got sheets %v, want %v", got, want)
+	}
+	rows, err := f.GetRows("Standings")
+	if err != nil {
+		t.Fatalf("reading rows: %v", err)
+	}
+	// The task columns go right after the info columns, and the footer counts the full solutions under them.
+	want := [][]string{
+		{"Rank", "Login", "Name", "Team", "D1-A", "D1-B", "D1-C", "D2-A", "D2-B", "Total"},
+		{"1", "my-login-1", "Alice", "Red", "100", "100", "40", "100", "50.5", "390.5"},
+		{"2", "my-login-2", "Bob", "", "100", "60", "0", "100", "100", "360"},
+		{"3", "my-login-3", "Carol", "", "100", "60", "0", "0", "0", "160"},
+		{"4", "my-login-5", "Eve", "", "0", "0", "0", "30", "0", "30"},
+		{"5", "my-login-4", "Dave", "Red", "20", "0", "0", "0", "0", "20"},
+		{"", "Full solutions", "", "", "3", "1", "0", "2", "1"},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal[[]string]) {
+		t.Errorf("got rows\n%q\nwant\n%q", rows, want)
+	}
+
+	// With only the rank column, the footer label is put into it.
+	err = env.store.Update(func(conf *Config) error {
+		conf.HideLogins = true
+		conf.DisplayNames = false
+		conf.DisplayTeams = false
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updating config: %v", err)
+	}
+	w = serve(http.HandlerFunc(p.ServeXLSX), "/export.xlsx")
+	f, err = excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("opening xlsx: %v", err)
+	}
+	defer f.Close()
+	rows, err = f.GetRows("Standings")
+	if err != nil {
+		t.Fatalf("reading rows: %v", err)
+	}
+	want = [][]string{
+		{"Rank", "D1-A", "D1-B", "D1-C", "D2-A", "D2-B", "Total"},
+		{"1", "100", "100", "40", "100", "50.5", "390.5"},
+		{"2", "100", "60", "0", "100", "100", "360"},
+		{"3", "100", "60", "0", "0", "0", "160"},
+		{"4", "0", "0", "0", "30", "0", "30"},
+		{"5", "20", "0", "0", "0", "0", "20"},
+		{"Full solutions", "3", "1", "0", "2", "1"},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal[[]string]) {
+		t.Errorf("got rows without info columns\n%q\nwant\n%q", rows, want)
+	}
+}